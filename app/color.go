@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// colorMode says when the output is colored.
+type colorMode int
+
+const (
+	colorAuto   colorMode = iota // only when writing to a terminal
+	colorAlways                  // --color=always
+	colorNever                   // --color=never
+)
+
+// colorPart is a part of the output that can be colored.
+type colorPart int
+
+const (
+	colorMatch     colorPart = iota // matched text in selected lines
+	colorSelected                   // the rest of selected lines
+	colorFileName                   // file names
+	colorLineNum                    // line numbers and columns
+	colorByteOff                    // byte offsets
+	colorSeparator                  // separators between the fields of a line
+	numColorParts
+)
+
+// colorNames maps the names of GREP_COLORS to the parts they color. mt sets
+// the color of matches in both selected and context lines; mygrep prints no
+// context lines, so it is the same as ms.
+var colorNames = map[string]colorPart{
+	"mt": colorMatch,
+	"ms": colorMatch,
+	"sl": colorSelected,
+	"fn": colorFileName,
+	"ln": colorLineNum,
+	"bn": colorByteOff,
+	"se": colorSeparator,
+}
+
+// colors holds the SGR parameters, such as "01;31", used to color each part of
+// the output. An empty parameter leaves that part uncolored, and so does a nil
+// *colors for all of them.
+type colors struct {
+	sgr     [numColorParts]string
+	noErase bool // don't erase to the end of the line after coloring
+}
+
+// defaultColors are the colors of GNU grep.
+var defaultColors = colors{
+	sgr: [numColorParts]string{
+		colorMatch:     "01;31",
+		colorFileName:  "35",
+		colorLineNum:   "32",
+		colorByteOff:   "32",
+		colorSeparator: "36",
+	},
+}
+
+// parseColors returns the default colors updated with a GREP_COLORS value,
+// a colon-separated list of name=SGR settings and boolean names such as ne.
+// Unknown names and malformed entries are ignored, as GNU grep does.
+func parseColors(spec string) *colors {
+	c := defaultColors
+	for _, entry := range strings.Split(spec, ":") {
+		name, value, hasValue := strings.Cut(entry, "=")
+		if !hasValue {
+			if name == "ne" {
+				c.noErase = true
+			}
+			continue
+		}
+		part, ok := colorNames[name]
+		if !ok || strings.Trim(value, "0123456789;") != "" {
+			continue
+		}
+		c.sgr[part] = value
+	}
+	return &c
+}
+
+// useColor reports whether the output goes in color: always or never as the
+// mode says, or in auto mode if stdout is a terminal and NO_COLOR is unset or
+// empty.
+func useColor(mode colorMode, stdout io.Writer) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := stdout.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// start appends the escape sequence that turns on the color of part. It appends
+// nothing when the part is uncolored, so uncolored output needs no special
+// case.
+func (c *colors) start(buf []byte, part colorPart) []byte {
+	if c == nil || c.sgr[part] == "" {
+		return buf
+	}
+	buf = append(buf, "\x1b["...)
+	buf = append(buf, c.sgr[part]...)
+	buf = append(buf, 'm')
+	return c.erase(buf)
+}
+
+// end appends the escape sequence that turns off what start turned on.
+func (c *colors) end(buf []byte, part colorPart) []byte {
+	if c == nil || c.sgr[part] == "" {
+		return buf
+	}
+	buf = append(buf, "\x1b[m"...)
+	return c.erase(buf)
+}
+
+// erase appends the sequence that erases to the end of the line, which keeps
+// the background color from spilling over when the terminal scrolls.
+func (c *colors) erase(buf []byte) []byte {
+	if c.noErase {
+		return buf
+	}
+	return append(buf, "\x1b[K"...)
+}
+
+// appendText appends text in the color of part.
+func (c *colors) appendText(buf []byte, part colorPart, text []byte) []byte {
+	buf = c.start(buf, part)
+	buf = append(buf, text...)
+	return c.end(buf, part)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseColors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		part    colorPart
+		want    string
+		noErase bool
+	}{
+		{"", colorMatch, "01;31", false},
+		{"", colorFileName, "35", false},
+		{"ms=04;32", colorMatch, "04;32", false},
+		{"mt=7", colorMatch, "7", false},
+		{"fn=:ne", colorFileName, "", true},
+		{"sl=1:se=33", colorSeparator, "33", false},
+		{"ln=bold", colorLineNum, "32", false}, // not an SGR parameter
+		{"xx=1:bn=34", colorByteOff, "34", false},
+	}
+	for _, tt := range tests {
+		c := parseColors(tt.spec)
+		if got := c.sgr[tt.part]; got != tt.want {
+			t.Errorf("parseColors(%q) part %d = %q, want %q", tt.spec, tt.part, got, tt.want)
+		}
+		if c.noErase != tt.noErase {
+			t.Errorf("parseColors(%q).noErase = %v, want %v", tt.spec, c.noErase, tt.noErase)
+		}
+	}
+}
+
+func TestColorsAppendText(t *testing.T) {
+	c := parseColors("ne")
+	if got := string(c.appendText(nil, colorMatch, []byte("x"))); got != "\x1b[01;31mx\x1b[m" {
+		t.Errorf("appendText() with ne = %q", got)
+	}
+	var none *colors
+	if got := string(none.appendText(nil, colorMatch, []byte("x"))); got != "x" {
+		t.Errorf("appendText() without colors = %q, want %q", got, "x")
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	var sb strings.Builder
+	if !useColor(colorAlways, &sb) {
+		t.Error("useColor(always) = false")
+	}
+	if useColor(colorNever, os.Stdout) {
+		t.Error("useColor(never) = true")
+	}
+	if useColor(colorAuto, &sb) {
+		t.Error("useColor(auto) = true for a writer that isn't a terminal")
+	}
+
+	// /dev/null is a character device, but not a terminal
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		defer devNull.Close()
+		if runtime.GOOS != "windows" && useColor(colorAuto, devNull) {
+			t.Errorf("useColor(auto) = true for %s", os.DevNull)
+		}
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if useColor(colorAuto, os.Stdout) {
+		t.Error("useColor(auto) = true with NO_COLOR set")
+	}
+	if !useColor(colorAlways, os.Stdout) {
+		t.Error("useColor(always) = false with NO_COLOR set")
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileNames are the files in a directory whose patterns exclude paths
+// from a recursive search. Patterns from files later in the list take
+// precedence, so .ignore can re-include what .gitignore excludes.
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+// ignoreRule is one pattern line of an ignore file, in gitignore syntax.
+type ignoreRule struct {
+	segments []string // the pattern split on slashes
+	anchored bool     // the pattern had a slash before its end: match from the directory of the file
+	negate   bool     // the pattern started with !: re-include what it matches
+	dirOnly  bool     // the pattern ended with a slash: match only directories
+}
+
+// ignoreFile holds the rules read from the ignore files of one directory.
+//
+// The directories above the root of a walk aren't paths of the walk, so their
+// ignore files take the root as dir, with base set.
+type ignoreFile struct {
+	dir   string // the directory, as a path of the walk
+	base  string // the path of dir relative to the ignore file's directory; empty if the same
+	rules []ignoreRule
+}
+
+// parseIgnoreRule parses a line of an ignore file. It returns false for blank
+// lines and comments.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return rule, false
+	}
+
+	if line[0] == '!' {
+		rule.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' && len(line) > 1 && (line[1] == '!' || line[1] == '#') {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	rule.segments = strings.Split(line, "/")
+	for i, seg := range rule.segments {
+		// gitignore negates a bracket expression with !, path.Match with ^
+		rule.segments[i] = strings.ReplaceAll(seg, "[!", "[^")
+	}
+	return rule, true
+}
+
+// match reports whether the rule matches a path, given relative to the
+// directory of its ignore file and split on slashes.
+func (r *ignoreRule) match(rel []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		return matchSegments(r.segments, rel[len(rel)-1:])
+	}
+	return matchSegments(r.segments, rel)
+}
+
+// matchSegments matches path segments against pattern segments. A ** segment
+// matches any number of path segments, at the end of the pattern at least one;
+// the other segments match a single path segment as in path.Match.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(name) > 0 // dir/** matches what is inside dir, not dir itself
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); !ok || err != nil {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignoreStack holds the ignore files of the directories from the root of the
+// repository, or of the walk outside one, down to the directory being visited.
+type ignoreStack []*ignoreFile
+
+// load returns the stack with the ignore files of dir added on top. Ignore
+// files that don't exist are skipped; other errors are returned along with the
+// stack.
+func (st ignoreStack) load(dir string) (ignoreStack, error) {
+	rules, err := readIgnoreFiles(osPath(dir))
+	if len(rules) == 0 {
+		return st, err
+	}
+	return append(st, &ignoreFile{dir: dir, rules: rules}), err
+}
+
+// loadAncestors returns the stack with the ignore files of the directories
+// above root added on top, from the root of the repository root is in down to
+// root's parent. The root of a repository is the nearest directory that holds
+// .git. Outside a repository, the stack is returned as is.
+func (st ignoreStack) loadAncestors(root string) (ignoreStack, error) {
+	abs, err := filepath.Abs(osPath(root))
+	if err != nil {
+		return st, err
+	}
+
+	var dirs []string // the directories above root, nearest first
+	for dir := abs; !isRepoRoot(dir); {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return st, nil
+		}
+		dirs = append(dirs, parent)
+		dir = parent
+	}
+
+	var errs []error
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rules, err := readIgnoreFiles(dirs[i])
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		base, err := filepath.Rel(dirs[i], abs)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		st = append(st, &ignoreFile{dir: root, base: base, rules: rules})
+	}
+	return st, errors.Join(errs...)
+}
+
+// isRepoRoot reports whether dir is the root of a git repository. .git is a
+// directory, or a file in worktrees and submodules.
+func isRepoRoot(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// readIgnoreFiles reads the rules of the ignore files in dir. Ignore files that
+// don't exist are skipped.
+func readIgnoreFiles(dir string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	var errs []error
+	for _, name := range ignoreFileNames {
+		rs, err := readIgnoreFile(filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		rules = append(rules, rs...)
+	}
+	return rules, errors.Join(errs...)
+}
+
+// readIgnoreFile reads the rules of an ignore file.
+func readIgnoreFile(name string) ([]ignoreRule, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if rule, ok := parseIgnoreRule(sc.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, sc.Err()
+}
+
+// ignored reports whether the path, found during the walk, is excluded. The
+// ignore file of the deepest directory is consulted first and, within a file,
+// the last matching rule decides.
+func (st ignoreStack) ignored(p string, isDir bool) bool {
+	p = filepath.ToSlash(p)
+	for i := len(st) - 1; i >= 0; i-- {
+		file := st[i]
+		rel := p
+		if file.dir != "" {
+			dir := strings.TrimSuffix(filepath.ToSlash(file.dir), "/") + "/"
+			if !strings.HasPrefix(p, dir) {
+				continue // not below the directory of the ignore file
+			}
+			rel = p[len(dir):]
+		}
+		if file.base != "" {
+			rel = filepath.ToSlash(file.base) + "/" + rel
+		}
+		segments := strings.Split(rel, "/")
+		for j := len(file.rules) - 1; j >= 0; j-- {
+			if file.rules[j].match(segments, isDir) {
+				return !file.rules[j].negate
+			}
+		}
+	}
+	return false
+}
+
+// isHidden reports whether a file name is hidden, that is, starts with a dot.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
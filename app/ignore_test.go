@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.log.txt", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"/build", "build", false, true},
+		{"/build", "src/build", false, false},
+		{"doc/*.txt", "doc/notes.txt", false, true},
+		{"doc/*.txt", "doc/server/arch.txt", false, false},
+		{"doc/*.txt", "src/doc/notes.txt", false, false},
+		{"**/foo", "foo", false, true},
+		{"**/foo", "a/b/foo", true, true},
+		{"**/foo/bar", "x/foo/bar", false, true},
+		{"abc/**", "abc/x/y", false, true},
+		{"abc/**", "abc", true, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**/b", "a/x/c", false, false},
+		{"file?.txt", "file1.txt", false, true},
+		{"[!a]bc", "xbc", false, true},
+		{"[!a]bc", "abc", false, false},
+		{"\\#hash", "#hash", false, true},
+		{"space\\ ", "space ", false, true},
+		{"trailing   ", "trailing", false, true},
+	}
+
+	for _, tt := range tests {
+		rule, ok := parseIgnoreRule(tt.pattern)
+		if !ok {
+			t.Errorf("parseIgnoreRule(%q) found no pattern", tt.pattern)
+			continue
+		}
+		if got := rule.match(strings.Split(tt.path, "/"), tt.isDir); got != tt.want {
+			t.Errorf("pattern %q on %q (dir %v) = %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParseIgnoreRuleSkipsNonPatterns(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "/", "\r"} {
+		if _, ok := parseIgnoreRule(line); ok {
+			t.Errorf("parseIgnoreRule(%q) returned a pattern", line)
+		}
+	}
+	rule, ok := parseIgnoreRule("!keep.log")
+	if !ok || !rule.negate {
+		t.Errorf("parseIgnoreRule(%q) = %+v, %v; want a negated pattern", "!keep.log", rule, ok)
+	}
+}
+
+func TestIgnoreStackPrecedence(t *testing.T) {
+	rules := func(lines ...string) []ignoreRule {
+		var rs []ignoreRule
+		for _, line := range lines {
+			r, _ := parseIgnoreRule(line)
+			rs = append(rs, r)
+		}
+		return rs
+	}
+	st := ignoreStack{
+		{dir: "", rules: rules("*.log", "!keep.log", "/top.txt")},
+		{dir: "sub", rules: rules("!*.log", "keep.log")},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"a.log", true},
+		{"keep.log", false},
+		{"top.txt", true},
+		{"sub/top.txt", false},
+		{"sub/a.log", false},   // re-included by the nested file
+		{"sub/keep.log", true}, // the last rule of the nested file wins
+		{"sub/x/b.log", false}, // the nested file applies further down too
+		{"other/a.log", true},  // the nested file only applies below sub
+		{"other/keep.log", false},
+	}
+	for _, tt := range tests {
+		if got := st.ignored(tt.path, false); got != tt.want {
+			t.Errorf("ignored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -1,145 +1,205 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
 )
 
-// Usage: echo <input_text> | your_program.sh -E <pattern>
+// Usage: echo <input_text> | your_program.sh -E <pattern> [file...]
 func main() {
-	if len(os.Args) < 3 || os.Args[1] != "-E" {
-		fmt.Fprintf(os.Stderr, "usage: mygrep -E <pattern>\n")
-		os.Exit(2) // 1 means no lines were selected, >1 means error
-	}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	pattern := os.Args[2]
+// stdinName is how standard input is named in front of its lines.
+const stdinName = "(standard input)"
 
-	line, err := io.ReadAll(os.Stdin) // assume we're only dealing with a single line
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: read input text: %v\n", err)
-		os.Exit(2)
-	}
-
-	ok, err := matchLine(line, pattern)
+// run executes mygrep with the given arguments and returns its exit status: 0
+// if any line was selected, 1 if none was, and 2 on a usage error, if a search
+// was aborted by --backtrack-limit or --timeout, or if another error occurred
+// and nothing was selected.
+//
+// Files that can't be read are reported on stderr and the remaining ones are
+// still searched. With -r or -R the directories among them are searched
+// recursively, and the current directory is searched if no file is given.
+//
+// Files are searched concurrently, by as many workers as GOMAXPROCS, and the
+// lines of each file are printed together. The files are printed in the order
+// they are named and found, the first of them as it is searched, or with
+// --sort none as their searches finish.
+// With -q everything stops at the first selected line.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(2)
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+		}
+		fmt.Fprintf(stderr, "%v\n", errUsage)
+		return 2 // 1 means no lines were selected, >1 means error
 	}
 
-	if !ok {
-		os.Exit(1)
+	var c *colors
+	if useColor(opts.color, stdout) {
+		opts.color = colorAlways
+		c = parseColors(os.Getenv("GREP_COLORS"))
+	} else {
+		opts.color = colorNever
 	}
 
-	// default exit code is 0 which means success
-}
-
-// matchLine checks if the pattern matches anywhere in the line.
-// It tries matching from every position in the line until a match is found.
-func matchLine(inputText []byte, pattern string) (bool, error) {
-	// Special case: pattern starts with ^ and ends with $, must match the whole line
-	if strings.HasPrefix(pattern, "^") && strings.HasSuffix(pattern, "$") {
-		pattern = pattern[1 : len(pattern)-1] // Remove both ^ and $, ^apple$ -> apple
-		return string(inputText) == pattern, nil
+	m, err := compile(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
 	}
 
-	// Special case: pattern starts with ^, must match from the beginning
-	if strings.HasPrefix(pattern, "^") {
-		pattern = pattern[1:] // Remove leading ^, ^apple -> apple
-		return strings.HasPrefix(string(inputText), pattern), nil
+	files := opts.files
+	if len(files) == 0 {
+		files = []string{"-"}
+		if opts.recursive {
+			files = []string{""} // the current directory, without a ./ prefix
+		}
 	}
 
-	// Special case: pattern ends with $, must match at the end
-	if strings.HasSuffix(pattern, "$") {
-		pattern = pattern[0 : len(pattern)-1] // Remove trailing $, apple$ -> apple
-		return strings.HasSuffix(string(inputText), pattern), nil
+	out := bufio.NewWriter(stdout)
+	var withName bool
+	switch opts.names {
+	case namesAuto:
+		withName = len(files) > 1 || opts.recursive && isDir(files[0])
+	case namesAlways:
+		withName = true
 	}
 
-	tokens, err := parseTokens(pattern)
-	if err != nil {
-		return false, err
+	// ctx is canceled as soon as -q has found a line, to stop the other
+	// searches and the walk, and found records that it did
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var found atomic.Bool
+
+	matched, failed, aborted := false, false, false
+	sched := &scheduler{
+		workers: runtime.GOMAXPROCS(0),
+		sorted:  !opts.unsorted,
+		done:    ctx.Done(),
+		search: func(t *task) {
+			if ctx.Err() != nil {
+				return
+			}
+			s := &searcher{m: m, opts: opts, out: &t.out, withName: withName, colors: c, done: ctx.Done()}
+			// The lines found are flushed while stdin waits, to keep up with a stream
+			ok, err := searchFile(s, t.path, &flushingReader{r: stdin, out: &t.out})
+			t.matched = ok
+			if opts.quiet && ok {
+				found.Store(true)
+				cancel()
+			}
+			if err != nil {
+				t.failed = true
+				t.aborted = errors.Is(err, regex.ErrBacktrackLimit) || errors.Is(err, regex.ErrTimeout)
+				t.msg = fmt.Sprintf("error: %v\n", err)
+			}
+		},
 	}
-
-	// Try matching from each position in the inputText
-	for start := 0; start < len(inputText); start++ {
-		if matchFromPosition(inputText, tokens, start) {
-			return true, nil
+	produce := func(add func(*task)) {
+		w := &walker{
+			follow:   opts.follow,
+			hidden:   opts.hidden,
+			noIgnore: opts.noIgnore,
+			done:     ctx.Done(),
+			onFile:   func(path string) { add(&task{path: path}) },
+			onError: func(err error) {
+				add(&task{msg: fmt.Sprintf("error: %v\n", err), failed: true})
+			},
+			onLoop: func(path string) {
+				add(&task{msg: fmt.Sprintf("warning: %s: recursive directory loop\n", path)})
+			},
+		}
+		for _, path := range files {
+			if ctx.Err() != nil {
+				return
+			}
+			if opts.recursive && path != "-" {
+				w.walk(path)
+			} else {
+				add(&task{path: path})
+			}
 		}
 	}
-
-	return false, nil
-}
-
-// matchFromPosition attempts to match all tokens sequentially starting from the given position.
-// It handles quantifiers like + (one or more) using backtracking.
-// It returns true if all tokens match consecutively from the start position.
-func matchFromPosition(inputText []byte, tokens []Token, startIndex int) bool {
-	return matchFromPositionRecursive(inputText, tokens, 0, startIndex)
-}
-
-// matchFromPositionRecursive recursively matches tokens with backtracking support.
-// It tries different match lengths for quantified tokens and backtracks on failure.
-//
-// Parameters:
-//   - inputText: the input string to match against
-//   - tokens: the parsed pattern tokens
-//   - tokenIndex: current token being matched
-//   - inputIndex: current position in the input text
-//
-// Returns true if all remaining tokens can be matched from the current position.
-func matchFromPositionRecursive(inputText []byte, tokens []Token, tokenIndex int, inputIndex int) bool {
-	// Base case: all tokens matched successfully
-	if tokenIndex >= len(tokens) {
-		return true
-	}
-
-	token := tokens[tokenIndex]
-
-	if token.Quantifier == OneOrMore {
-		// + quantifier: match one or more times with backtracking
-		// Must match at least once
-		if inputIndex >= len(inputText) || !matchToken(token, inputText[inputIndex]) {
-			return false
+	head := func(t *task) {
+		if err := t.out.release(out); err != nil {
+			out.Flush()
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			failed = true
 		}
-
-		// Try matching 1, 2, 3, ... times (backtracking)
-		// Start with minimum (1) and try increasing matches
-		for i := inputIndex; i < len(inputText) && matchToken(token, inputText[i]); i++ {
-			// Try matching the rest of the pattern with current match count
-			if matchFromPositionRecursive(inputText, tokens, tokenIndex+1, i+1) {
-				return true // Found a successful match
+	}
+	emit := func(t *task) {
+		matched = matched || t.matched
+		failed = failed || t.failed
+		aborted = aborted || t.aborted
+		// the message goes to stderr after the output that comes before it
+		if t.msg != "" {
+			if err := out.Flush(); err == nil {
+				io.WriteString(stderr, t.msg)
 			}
-			// If failed, backtrack and try matching one more character
 		}
+	}
+	sched.run(produce, head, emit)
 
-		return false // All attempts failed
-
-	} else if token.Quantifier == ZeroOrOne {
-		// ? quantifier: match zero or one time with backtracking
-		// Try matching 0 times first (skip the token)
-		if matchFromPositionRecursive(inputText, tokens, tokenIndex+1, inputIndex) {
-			return true
-		}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		failed = true
+	}
 
-		// Try matching 1 time (consume one character)
-		if inputIndex < len(inputText) && matchToken(token, inputText[inputIndex]) {
-			return matchFromPositionRecursive(inputText, tokens, tokenIndex+1, inputIndex+1)
-		}
+	switch {
+	case found.Load():
+		return 0 // -q stops at the first line, whatever else happened
+	case aborted:
+		return 2 // a search that gave up may have missed lines
+	case matched:
+		return 0 // default exit code is 0 which means success
+	case failed:
+		return 2
+	default:
+		return 1
+	}
+}
 
-		return false // Both attempts failed
+// isDir reports whether path, or the current directory if path is empty, is a
+// directory.
+func isDir(path string) bool {
+	info, err := os.Stat(osPath(path))
+	return err == nil && info.IsDir()
+}
 
-	} else {
-		// No quantifier: match exactly once
-		if inputIndex >= len(inputText) {
-			return false
+// searchFile searches the file at path, or stdin if path is -, and reports
+// whether any line matched.
+func searchFile(s *searcher, path string, stdin io.Reader) (bool, error) {
+	if path == "-" {
+		ok, err := s.search(stdin, stdinName)
+		if err != nil {
+			return ok, fmt.Errorf("%s: %w", stdinName, err)
 		}
+		return ok, nil
+	}
 
-		if matchToken(token, inputText[inputIndex]) {
-			// Recursively match the rest of the pattern
-			return matchFromPositionRecursive(inputText, tokens, tokenIndex+1, inputIndex+1)
-		}
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		return false, fmt.Errorf("%s: is a directory", path)
+	}
 
-		return false // Token doesn't match
+	ok, err := s.search(f, path)
+	if err != nil {
+		return ok, fmt.Errorf("%s: %w", path, err)
 	}
+	return ok, nil
 }
@@ -1,652 +1,513 @@
 package main
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestMatchLine(t *testing.T) {
+// writeFiles creates the given files, relative to a new temporary directory,
+// and changes into that directory for the rest of the test.
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+}
+
+// runCommand runs mygrep with args and no stdin and returns its results.
+func runCommand(args ...string) (stdout, stderr string, status int) {
+	var out, errOut strings.Builder
+	status = run(args, strings.NewReader(""), &out, &errOut)
+	return out.String(), errOut.String(), status
+}
+
+func TestRunFiles(t *testing.T) {
+	writeFiles(t, map[string]string{
+		"a.txt": "apple\nbanana\n",
+		"b.txt": "cherry\napple pie\n",
+		"c.txt": "nothing here\n",
+	})
+
 	tests := []struct {
-		name    string
-		line    []byte
-		pattern string
-		want    bool
-		wantErr bool
+		name       string
+		args       []string
+		stdin      string
+		wantOut    string
+		wantErr    string // substring expected on stderr
+		wantStatus int
 	}{
-		// \d 패턴 테스트
-		{
-			name:    "\\d matches digit",
-			line:    []byte("hello123"),
-			pattern: "\\d",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\d no match",
-			line:    []byte("hello"),
-			pattern: "\\d",
-			want:    false,
-			wantErr: false,
-		},
-		// \w 패턴 테스트
-		{
-			name:    "\\w matches word char",
-			line:    []byte("hello"),
-			pattern: "\\w",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\w no match",
-			line:    []byte("!!!"),
-			pattern: "\\w",
-			want:    false,
-			wantErr: false,
-		},
-		// 단일 문자 테스트
-		{
-			name:    "single char match",
-			line:    []byte("apple"),
-			pattern: "a",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "single char no match",
-			line:    []byte("apple"),
-			pattern: "z",
-			want:    false,
-			wantErr: false,
-		},
-		// Positive character groups 테스트
-		{
-			name:    "[abc] matches a",
-			line:    []byte("apple"),
-			pattern: "[abc]",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[abc] matches b",
-			line:    []byte("banana"),
-			pattern: "[abc]",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[abc] no match",
-			line:    []byte("xyz"),
-			pattern: "[abc]",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "[xyz] matches z",
-			line:    []byte("buzz"),
-			pattern: "[xyz]",
-			want:    true,
-			wantErr: false,
-		},
-		// Negative character groups 테스트
-		{
-			name:    "[^abc] matches cat (has t)",
-			line:    []byte("cat"),
-			pattern: "[^abc]",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[^abc] does not match cab",
-			line:    []byte("cab"),
-			pattern: "[^abc]",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "[^ab] matches abc (has c)",
-			line:    []byte("abc"),
-			pattern: "[^ab]",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[^xyz] matches hello",
-			line:    []byte("hello"),
-			pattern: "[^xyz]",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[^xyz] does not match xyz",
-			line:    []byte("xyz"),
-			pattern: "[^xyz]",
-			want:    false,
-			wantErr: false,
-		},
-		// Pattern sequence tests
-		{
-			name:    "\\d apple matches 1 apple",
-			line:    []byte("1 apple"),
-			pattern: "\\d apple",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\d apple does not match 1 orange",
-			line:    []byte("1 orange"),
-			pattern: "\\d apple",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "\\d\\d\\d apple matches 100 apples",
-			line:    []byte("100 apples"),
-			pattern: "\\d\\d\\d apple",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\d\\d\\d apple does not match 1 apple",
-			line:    []byte("1 apple"),
-			pattern: "\\d\\d\\d apple",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "\\d \\w\\w\\ws matches 3 dogs",
-			line:    []byte("3 dogs"),
-			pattern: "\\d \\w\\w\\ws",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\d \\w\\w\\ws matches 4 cats",
-			line:    []byte("4 cats"),
-			pattern: "\\d \\w\\w\\ws",
-			want:    true,
-			wantErr: false,
-		},
 		{
-			name:    "\\d \\w\\w\\ws does not match 1 dog",
-			line:    []byte("1 dog"),
-			pattern: "\\d \\w\\w\\ws",
-			want:    false,
-			wantErr: false,
+			name:       "single file has no prefix",
+			args:       []string{"-E", "apple", "a.txt"},
+			wantOut:    "apple\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "multi-char sequence abc",
-			line:    []byte("xyzabcdef"),
-			pattern: "abc",
-			want:    true,
-			wantErr: false,
+			name:       "several files are prefixed",
+			args:       []string{"-E", "apple", "a.txt", "b.txt", "c.txt"},
+			wantOut:    "a.txt:apple\nb.txt:apple pie\n",
+			wantStatus: 0,
 		},
-		// Start of string anchor tests
 		{
-			name:    "^apple matches apple pie",
-			line:    []byte("apple pie"),
-			pattern: "^apple",
-			want:    true,
-			wantErr: false,
+			name:       "-H forces the prefix",
+			args:       []string{"-E", "-H", "apple", "a.txt"},
+			wantOut:    "a.txt:apple\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "^apple does not match green apple",
-			line:    []byte("green apple"),
-			pattern: "^apple",
-			want:    false,
-			wantErr: false,
+			name:       "-h suppresses the prefix",
+			args:       []string{"-E", "-h", "apple", "a.txt", "b.txt"},
+			wantOut:    "apple\napple pie\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "^apple matches apple exactly",
-			line:    []byte("apple"),
-			pattern: "^apple",
-			want:    true,
-			wantErr: false,
+			name:       "-v selects the lines that don't match",
+			args:       []string{"-E", "-v", "apple", "a.txt", "b.txt"},
+			wantOut:    "a.txt:banana\nb.txt:cherry\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "^log matches log message",
-			line:    []byte("log message"),
-			pattern: "^log",
-			want:    true,
-			wantErr: false,
+			name:       "-v with every line matching",
+			args:       []string{"-E", "-v", "\\w", "a.txt"},
+			wantStatus: 1,
 		},
-		// End of string anchor tests
 		{
-			name:    "apple$ matches green apple",
-			line:    []byte("green apple"),
-			pattern: "apple$",
-			want:    true,
-			wantErr: false,
+			name:       "-c counts per file",
+			args:       []string{"-E", "-c", "apple", "a.txt", "b.txt", "c.txt"},
+			wantOut:    "a.txt:1\nb.txt:1\nc.txt:0\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "apple$ does not match apple pie",
-			line:    []byte("apple pie"),
-			pattern: "apple$",
-			want:    false,
-			wantErr: false,
+			name:       "-l lists files with matches",
+			args:       []string{"-E", "-l", "apple", "a.txt", "b.txt", "c.txt"},
+			wantOut:    "a.txt\nb.txt\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "apple$ matches apple exactly",
-			line:    []byte("apple"),
-			pattern: "apple$",
-			want:    true,
-			wantErr: false,
+			name:       "-L lists files without matches",
+			args:       []string{"-E", "-L", "apple", "a.txt", "b.txt", "c.txt"},
+			wantOut:    "c.txt\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "world$ matches hello world",
-			line:    []byte("hello world"),
-			pattern: "world$",
-			want:    true,
-			wantErr: false,
+			name:       "-q prints nothing",
+			args:       []string{"-E", "-q", "apple", "a.txt", "b.txt"},
+			wantStatus: 0,
 		},
 		{
-			name:    "strawberry$ matches orange_strawberry",
-			line:    []byte("orange_strawberry"),
-			pattern: "strawberry$",
-			want:    true,
-			wantErr: false,
+			name:       "-q without a match",
+			args:       []string{"-E", "-q", "grape", "a.txt", "b.txt"},
+			wantStatus: 1,
 		},
 		{
-			name:    "strawberry$ does not match strawberry_orange",
-			line:    []byte("strawberry_orange"),
-			pattern: "strawberry$",
-			want:    false,
-			wantErr: false,
+			name:       "--vimgrep shows the name of a single file",
+			args:       []string{"-E", "--vimgrep", "p+", "b.txt"},
+			wantOut:    "b.txt:2:2:apple pie\nb.txt:2:7:apple pie\n",
+			wantStatus: 0,
 		},
-		// Both anchors: ^...$ (exact match)
 		{
-			name:    "^apple$ matches apple exactly",
-			line:    []byte("apple"),
-			pattern: "^apple$",
-			want:    true,
-			wantErr: false,
+			name:       "no file matches",
+			args:       []string{"-E", "grape", "a.txt", "b.txt"},
+			wantStatus: 1,
 		},
 		{
-			name:    "^apple$ does not match apple pie",
-			line:    []byte("apple pie"),
-			pattern: "^apple$",
-			want:    false,
-			wantErr: false,
+			name:       "stdin without files",
+			args:       []string{"-E", "b"},
+			stdin:      "abc\nxyz\n",
+			wantOut:    "abc\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "^apple$ does not match green apple",
-			line:    []byte("green apple"),
-			pattern: "^apple$",
-			want:    false,
-			wantErr: false,
+			name:       "- reads stdin among files",
+			args:       []string{"-E", "apple", "-", "c.txt"},
+			stdin:      "green apple\n",
+			wantOut:    "(standard input):green apple\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "^cat$ matches cat exactly",
-			line:    []byte("cat"),
-			pattern: "^cat$",
-			want:    true,
-			wantErr: false,
+			name:       "missing file with a match elsewhere",
+			args:       []string{"-E", "apple", "missing.txt", "a.txt"},
+			wantOut:    "a.txt:apple\n",
+			wantErr:    "missing.txt",
+			wantStatus: 0,
 		},
 		{
-			name:    "^cat$ does not match cats",
-			line:    []byte("cats"),
-			pattern: "^cat$",
-			want:    false,
-			wantErr: false,
+			name:       "missing file and no match",
+			args:       []string{"-E", "grape", "a.txt", "missing.txt"},
+			wantErr:    "missing.txt",
+			wantStatus: 2,
 		},
-		// + quantifier tests (one or more)
 		{
-			name:    "ca+ts matches cats (1 a)",
-			line:    []byte("cats"),
-			pattern: "ca+ts",
-			want:    true,
-			wantErr: false,
+			name:       "aborted search after a match",
+			args:       []string{"-E", "-o", "--backtrack-limit=15", "a?a?a?a?a?aaaaa"},
+			stdin:      "aaaaaaaaaa\naaaaa\n",
+			wantOut:    "aaaaaaaaaa\n",
+			wantErr:    "step limit exceeded",
+			wantStatus: 2,
 		},
 		{
-			name:    "ca+ts matches caats (2 a's)",
-			line:    []byte("caats"),
-			pattern: "ca+ts",
-			want:    true,
-			wantErr: false,
+			name:       "timed out search",
+			args:       []string{"-E", "--timeout=1ns", "apple", "a.txt"},
+			wantErr:    "timed out",
+			wantStatus: 2,
 		},
 		{
-			name:    "ca+ts matches caaats (3 a's)",
-			line:    []byte("caaats"),
-			pattern: "ca+ts",
-			want:    true,
-			wantErr: false,
+			name:       "usage error",
+			args:       []string{"apple"},
+			wantErr:    "usage:",
+			wantStatus: 2,
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			status := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if status != tt.wantStatus {
+				t.Errorf("run() = %d, want %d (stderr %q)", status, tt.wantStatus, stderr.String())
+			}
+			if stdout.String() != tt.wantOut {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantOut)
+			}
+			if tt.wantErr == "" && stderr.Len() > 0 {
+				t.Errorf("unexpected stderr %q", stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want it to mention %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunRecursive(t *testing.T) {
+	writeFiles(t, map[string]string{
+		"b.txt":         "apple\n",
+		"a/x.txt":       "apple pie\n",
+		"a/b/deep.txt":  "red apple\n",
+		"c/apple.txt":   "no match\n",
+		"outside/z.txt": "apple tree\n",
+	})
+	if err := os.Symlink("outside", "link"); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink("..", filepath.Join("a", "b", "up")); err != nil {
+		t.Fatal(err)
+	}
+	// A socket is neither a file nor a directory and must be skipped
+	if l, err := net.Listen("unix", filepath.Join("c", "sock")); err == nil {
+		defer l.Close()
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantOut    string
+		wantErr    string
+		wantStatus int
+	}{
 		{
-			name:    "ca+ts does not match cts (0 a's)",
-			line:    []byte("cts"),
-			pattern: "ca+ts",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "a+ matches a",
-			line:    []byte("a"),
-			pattern: "a+",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a+ matches aaa",
-			line:    []byte("aaa"),
-			pattern: "a+",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a+ does not match b",
-			line:    []byte("b"),
-			pattern: "a+",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "a+b matches ab",
-			line:    []byte("ab"),
-			pattern: "a+b",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a+b matches aaab",
-			line:    []byte("aaab"),
-			pattern: "a+b",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a+b does not match b",
-			line:    []byte("b"),
-			pattern: "a+b",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "a+b+ matches ab",
-			line:    []byte("ab"),
-			pattern: "a+b+",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a+b+ matches aaabbb",
-			line:    []byte("aaabbb"),
-			pattern: "a+b+",
-			want:    true,
-			wantErr: false,
-		},
-		// Backtracking tests (cases where greedy matching would fail)
-		{
-			name:    "ca+at matches caaats (backtracking needed)",
-			line:    []byte("caaats"),
-			pattern: "ca+at",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "ca+at matches caats (no backtracking needed)",
-			line:    []byte("caats"),
-			pattern: "ca+at",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "ca+at does not match cts",
-			line:    []byte("cts"),
-			pattern: "ca+at",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "ca+at does not match caaaa (no 't' at end)",
-			line:    []byte("caaaa"),
-			pattern: "ca+at",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "a+b matches aaab (backtracking needed)",
-			line:    []byte("aaab"),
-			pattern: "a+b",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\d+apple matches 123apple (backtracking might be needed)",
-			line:    []byte("123apple"),
-			pattern: "\\d+apple",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\d+ apple matches 100 apples (backtracking for space)",
-			line:    []byte("100 apples"),
-			pattern: "\\d+ apple",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a+b+c matches aaabbbc (multiple quantifiers with backtracking)",
-			line:    []byte("aaabbbc"),
-			pattern: "a+b+c",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a+b+c matches abc (minimal match)",
-			line:    []byte("abc"),
-			pattern: "a+b+c",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[abc]+d matches abcd (backtracking with char class)",
-			line:    []byte("abcd"),
-			pattern: "[abc]+d",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[abc]+d matches aaabbbcccd (backtracking with char class)",
-			line:    []byte("aaabbbcccd"),
-			pattern: "[abc]+d",
-			want:    true,
-			wantErr: false,
-		},
-		// Literal backslash tests
-		{
-			name:    "\\\\ matches backslash character",
-			line:    []byte("a\\b"),
-			pattern: "a\\\\b",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\\\ does not match when no backslash present",
-			line:    []byte("ab"),
-			pattern: "a\\\\b",
-			want:    false,
-			wantErr: false,
-		},
-		{
-			name:    "\\\\d matches backslash followed by 'd'",
-			line:    []byte("test\\d123"),
-			pattern: "\\\\d",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\\\d does not match digit (should match literal \\d)",
-			line:    []byte("test5"),
-			pattern: "\\\\d",
-			want:    false,
-			wantErr: false,
-		},
-		// Zero or One (?) quantifier tests
-		{
-			name:    "dogs? matches dog (0 s)",
-			line:    []byte("dog"),
-			pattern: "dogs?",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "dogs? matches dogs (1 s)",
-			line:    []byte("dogs"),
-			pattern: "dogs?",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "colou?r matches color (0 u)",
-			line:    []byte("color"),
-			pattern: "colou?r",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "colou?r matches colour (1 u)",
-			line:    []byte("colour"),
-			pattern: "colou?r",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a? matches empty at start",
-			line:    []byte("b"),
-			pattern: "a?b",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "a? matches single a",
-			line:    []byte("ab"),
-			pattern: "a?b",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\\\d? matches 0 digits",
-			line:    []byte("apple"),
-			pattern: "\\d?apple",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "\\\\d? matches 1 digit",
-			line:    []byte("1apple"),
-			pattern: "\\d?apple",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[abc]? matches 0 chars",
-			line:    []byte("dog"),
-			pattern: "[abc]?dog",
-			want:    true,
-			wantErr: false,
-		},
-		{
-			name:    "[abc]? matches 1 char",
-			line:    []byte("adog"),
-			pattern: "[abc]?dog",
-			want:    true,
-			wantErr: false,
-		},
-		// Dot (.) metacharacter tests
-		{
-			name:    "d.g matches dog",
-			line:    []byte("dog"),
-			pattern: "d.g",
-			want:    true,
-			wantErr: false,
+			name:       "-r defaults to the current directory",
+			args:       []string{"-E", "-r", "apple"},
+			wantOut:    "a/b/deep.txt:red apple\na/x.txt:apple pie\nb.txt:apple\noutside/z.txt:apple tree\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "d.g matches dag",
-			line:    []byte("dag"),
-			pattern: "d.g",
-			want:    true,
-			wantErr: false,
+			name:       "-r keeps the given prefix",
+			args:       []string{"-E", "-r", "apple", "./a"},
+			wantOut:    "./a/b/deep.txt:red apple\n./a/x.txt:apple pie\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "d.g matches d1g",
-			line:    []byte("d1g"),
-			pattern: "d.g",
-			want:    true,
-			wantErr: false,
+			name:       "-r follows symbolic links on the command line",
+			args:       []string{"-E", "-r", "apple", "link"},
+			wantOut:    "link/z.txt:apple tree\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "d.g matches d@g",
-			line:    []byte("d@g"),
-			pattern: "d.g",
-			want:    true,
-			wantErr: false,
+			name:       "-r on a single file has no prefix",
+			args:       []string{"-E", "-r", "apple", "b.txt"},
+			wantOut:    "apple\n",
+			wantStatus: 0,
 		},
 		{
-			name:    "d.g does not match cog (first char mismatch)",
-			line:    []byte("cog"),
-			pattern: "d.g",
-			want:    false,
-			wantErr: false,
+			name: "-R follows symbolic links and stops at loops",
+			args: []string{"-E", "-R", "apple"},
+			wantOut: "a/b/deep.txt:red apple\na/x.txt:apple pie\nb.txt:apple\n" +
+				"link/z.txt:apple tree\noutside/z.txt:apple tree\n",
+			wantErr:    "a/b/up: recursive directory loop",
+			wantStatus: 0,
 		},
 		{
-			name:    "d.g does not match dg (missing middle char)",
-			line:    []byte("dg"),
-			pattern: "d.g",
-			want:    false,
-			wantErr: false,
+			name:       "directory without -r",
+			args:       []string{"-E", "apple", "a"},
+			wantErr:    "a: is a directory",
+			wantStatus: 2,
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, status := runCommand(tt.args...)
+			if status != tt.wantStatus {
+				t.Errorf("run() = %d, want %d (stderr %q)", status, tt.wantStatus, stderr)
+			}
+			if stdout != filepath.FromSlash(tt.wantOut) {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOut)
+			}
+			if tt.wantErr == "" && stderr != "" {
+				t.Errorf("unexpected stderr %q", stderr)
+			}
+			if !strings.Contains(stderr, filepath.FromSlash(tt.wantErr)) {
+				t.Errorf("stderr = %q, want it to mention %q", stderr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunIgnore(t *testing.T) {
+	writeFiles(t, map[string]string{
+		".gitignore":        "*.log\nbuild/\n/top.txt\n!keep.log\n",
+		".ignore":           "generated.txt\n",
+		".hidden.txt":       "apple\n",
+		".config/x.txt":     "apple\n",
+		"a.txt":             "apple\n",
+		"debug.log":         "apple\n",
+		"keep.log":          "apple\n",
+		"top.txt":           "apple\n",
+		"generated.txt":     "apple\n",
+		"build/out.txt":     "apple\n",
+		"src/top.txt":       "apple\n",
+		"src/.gitignore":    "!*.log\n",
+		"src/trace.log":     "apple\n",
+		"src/build/gen.txt": "apple\n",
+	})
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantFiles []string
+	}{
 		{
-			name:    "... matches any 3 chars",
-			line:    []byte("abc"),
-			pattern: "...",
-			want:    true,
-			wantErr: false,
+			name:      "ignore files and hidden files are honored",
+			args:      []string{"-E", "-r", "apple"},
+			wantFiles: []string{"a.txt", "keep.log", "src/top.txt", "src/trace.log"},
 		},
 		{
-			name:    "... matches 123",
-			line:    []byte("123"),
-			pattern: "...",
-			want:    true,
-			wantErr: false,
+			name:      "--hidden",
+			args:      []string{"-E", "-r", "--hidden", "apple"},
+			wantFiles: []string{".config/x.txt", ".hidden.txt", "a.txt", "keep.log", "src/top.txt", "src/trace.log"},
 		},
 		{
-			name:    ".+ matches one or more chars",
-			line:    []byte("hello"),
-			pattern: ".+",
-			want:    true,
-			wantErr: false,
+			name: "--no-ignore",
+			args: []string{"-E", "-r", "--no-ignore", "apple"},
+			wantFiles: []string{"a.txt", "build/out.txt", "debug.log", "generated.txt", "keep.log",
+				"src/build/gen.txt", "src/top.txt", "src/trace.log", "top.txt"},
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, status := runCommand(tt.args...)
+			if status != 0 {
+				t.Fatalf("run() = %d, stderr %q", status, stderr)
+			}
+			var want strings.Builder
+			for _, name := range tt.wantFiles {
+				want.WriteString(filepath.FromSlash(name) + ":apple\n")
+			}
+			if stdout != want.String() {
+				t.Errorf("stdout = %q, want %q", stdout, want.String())
+			}
+		})
+	}
+}
+
+func TestRunIgnoreAncestors(t *testing.T) {
+	writeFiles(t, map[string]string{
+		".git/HEAD":       "ref: refs/heads/main\n",
+		".gitignore":      "*.log\n/a/b\n",
+		"a/.ignore":       "skip.txt\n",
+		"a/x.txt":         "apple\n",
+		"a/z.log":         "apple\n",
+		"a/b/y.txt":       "apple\n",
+		"a/c/skip.txt":    "apple\n",
+		"a/c/keep.txt":    "apple\n",
+		"a/c/b/found.txt": "apple\n",
+	})
+
+	tests := []struct {
+		name      string
+		dir       string
+		args      []string
+		wantFiles []string
+	}{
 		{
-			name:    "a.?b matches ab (0 chars)",
-			line:    []byte("ab"),
-			pattern: "a.?b",
-			want:    true,
-			wantErr: false,
+			name:      "root below the repository root",
+			args:      []string{"-E", "-r", "apple", "a"},
+			wantFiles: []string{"a/c/b/found.txt", "a/c/keep.txt", "a/x.txt"},
 		},
 		{
-			name:    "a.?b matches acb (1 char)",
-			line:    []byte("acb"),
-			pattern: "a.?b",
-			want:    true,
-			wantErr: false,
+			name:      "two levels below",
+			args:      []string{"-E", "-r", "apple", "a/c"},
+			wantFiles: []string{"a/c/b/found.txt", "a/c/keep.txt"},
 		},
 		{
-			name:    "c.t matches cat",
-			line:    []byte("cat"),
-			pattern: "c.t",
-			want:    true,
-			wantErr: false,
+			name:      "current directory below the repository root",
+			dir:       "a",
+			args:      []string{"-E", "-r", "apple"},
+			wantFiles: []string{"c/b/found.txt", "c/keep.txt", "x.txt"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := matchLine(tt.line, tt.pattern)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("matchLine() error = %v, wantErr %v", err, tt.wantErr)
-				return
+			if tt.dir != "" {
+				t.Chdir(tt.dir)
 			}
-			if got != tt.want {
-				t.Errorf("matchLine() = %v, want %v", got, tt.want)
+			stdout, stderr, status := runCommand(tt.args...)
+			if status != 0 {
+				t.Fatalf("run() = %d, stderr %q", status, stderr)
+			}
+			var want strings.Builder
+			for _, name := range tt.wantFiles {
+				want.WriteString(filepath.FromSlash(name) + ":apple\n")
+			}
+			if stdout != want.String() {
+				t.Errorf("stdout = %q, want %q", stdout, want.String())
 			}
 		})
 	}
 }
+
+func TestRunParallel(t *testing.T) {
+	files := make(map[string]string)
+	var want []string
+	for i := range 50 {
+		name := fmt.Sprintf("f%02d.txt", i)
+		files[name] = strings.Repeat("apple\nbanana\n", 200)
+		want = append(want, name)
+	}
+	writeFiles(t, files)
+	// fileOutput is what a file is expected to print
+	fileOutput := func(name string) string { return strings.Repeat(name+":apple\n", 200) }
+
+	t.Run("--sort none keeps files contiguous", func(t *testing.T) {
+		stdout, stderr, status := runCommand("-E", "-r", "--sort=none", "apple")
+		if status != 0 {
+			t.Fatalf("run() = %d, stderr %q", status, stderr)
+		}
+		// Each file's lines come out together, in whatever order the files do
+		rest := stdout
+		seen := make(map[string]bool)
+		for rest != "" {
+			name, _, _ := strings.Cut(rest, ":")
+			block := fileOutput(name)
+			if seen[name] || !strings.HasPrefix(rest, block) {
+				t.Fatalf("lines of %s are not contiguous in %q", name, stdout)
+			}
+			seen[name] = true
+			rest = rest[len(block):]
+		}
+		if len(seen) != len(want) {
+			t.Errorf("got output for %d files, want %d", len(seen), len(want))
+		}
+	})
+
+	t.Run("files are in the order they are found", func(t *testing.T) {
+		stdout, _, _ := runCommand("-E", "-r", "apple")
+		var sb strings.Builder
+		for _, name := range want {
+			sb.WriteString(fileOutput(name))
+		}
+		if stdout != sb.String() {
+			t.Errorf("output isn't in path order")
+		}
+	})
+}
+
+// TestRunStreamsStdin checks that lines read from stdin are printed as they
+// come, without waiting for the end of the input.
+func TestRunStreamsStdin(t *testing.T) {
+	stdin, input := io.Pipe()
+	output, stdout := io.Pipe()
+	status := make(chan int)
+	go func() {
+		status <- run([]string{"-E", "hello"}, stdin, stdout, io.Discard)
+		stdout.Close()
+	}()
+
+	go io.WriteString(input, "hello\nworld\n")
+	lines := make(chan string)
+	go func() {
+		line, _ := bufio.NewReader(output).ReadString('\n')
+		lines <- line
+		io.Copy(io.Discard, output)
+	}()
+	select {
+	case line := <-lines:
+		if line != "hello\n" {
+			t.Errorf("first line = %q, want %q", line, "hello\n")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no output before the end of stdin")
+	}
+
+	input.Close()
+	if got := <-status; got != 0 {
+		t.Errorf("run() = %d, want 0", got)
+	}
+}
+
+func TestRunQuietStopsEarly(t *testing.T) {
+	writeFiles(t, map[string]string{"needle.txt": "needle\n"})
+
+	for _, sort := range []string{"--sort=path", "--sort=none"} {
+		// stdin stays open, so its search never ends on its own
+		stdin, input := io.Pipe()
+		status := make(chan int)
+		go func() {
+			status <- run([]string{"-E", "-q", sort, "needle", "-", "needle.txt"}, stdin, io.Discard, io.Discard)
+		}()
+
+		select {
+		case got := <-status:
+			if got != 0 {
+				t.Errorf("%s: run() = %d, want 0", sort, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: -q waited for stdin after a match", sort)
+		}
+		input.Close()
+	}
+}
+
+func TestRunColor(t *testing.T) {
+	writeFiles(t, map[string]string{"a.txt": "apple\n"})
+
+	t.Setenv("GREP_COLORS", "ms=4:fn=:se=:ne")
+	stdout, _, _ := runCommand("-E", "--color=always", "-H", "p+", "a.txt")
+	if want := "a.txt:a\x1b[4mpp\x1b[mle\n"; stdout != want {
+		t.Errorf("--color=always: stdout = %q, want %q", stdout, want)
+	}
+
+	// stdout isn't a terminal here, so auto mode doesn't color
+	stdout, _, _ = runCommand("-E", "--color=auto", "p+", "a.txt")
+	if want := "apple\n"; stdout != want {
+		t.Errorf("--color=auto: stdout = %q, want %q", stdout, want)
+	}
+}
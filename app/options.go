@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// options holds the parsed command line.
+type options struct {
+	patterns       []string      // from -e, or else the first positional argument
+	files          []string      // files to search; - is standard input
+	names          nameMode      // when to prefix output lines with the file name
+	recursive      bool          // -r or -R: search directories recursively
+	follow         bool          // -R: follow symbolic links inside directories
+	hidden         bool          // search hidden files and directories
+	noIgnore       bool          // don't honor .gitignore and .ignore files
+	unsorted       bool          // --sort none: print files as their searches finish
+	which          bool          // print the indices of the patterns that matched each line
+	invert         bool          // -v: select the lines that don't match
+	only           bool          // -o: print only the matched parts of lines
+	lineNumbers    bool          // -n: show the number of each line
+	byteOffset     bool          // -b: show the byte offset of each line, or with -o of each match
+	column         bool          // --column: show the column of the first match of each line
+	vimgrep        bool          // --vimgrep: show every match as file:line:column:text
+	count          bool          // -c: print the number of selected lines of each file
+	list           listMode      // -l or -L: print only the names of files
+	quiet          bool          // -q: print nothing and stop at the first selected line
+	color          colorMode     // when to color the output; run resolves colorAuto
+	crlf           bool          // lines may end with \r\n as well as \n
+	timeout        time.Duration // wall-clock limit for the whole search; 0 means none
+	backtrackLimit int           // backtracking steps per search; 0 means unlimited
+	longest        bool          // leftmost-longest (POSIX) match semantics
+}
+
+// summarizes reports whether each file is summarized, with -c, -l, -L or -q,
+// rather than shown line by line.
+func (o *options) summarizes() bool {
+	return o.count || o.list != listNone || o.quiet
+}
+
+// listMode says which file names -l and -L print.
+type listMode int
+
+const (
+	listNone        listMode = iota
+	listMatching             // -l: files with a selected line
+	listNonMatching          // -L: files without any
+)
+
+// nameMode says when output lines are prefixed with the name of their file.
+type nameMode int
+
+const (
+	namesAuto   nameMode = iota // only when searching more than one file
+	namesAlways                 // -H
+	namesNever                  // -h
+)
+
+// errUsage is returned when the command line doesn't have the expected shape.
+var errUsage = errors.New("usage: mygrep -E [--timeout=DURATION] [--backtrack-limit=N] [--longest] [--which] [-v] [-o] [-c] [-l | -L] [-q] [-n] [-b] [--column] [--vimgrep] [--color[=WHEN]] [--crlf] [-H | -h] [-r | -R] [--hidden] [--no-ignore] [--sort=path|none] {-e <pattern>... | <pattern>} [file...]")
+
+// parseArgs parses the command line arguments, not including the program name.
+//
+// Long options take their value either as --name=value or as the next argument,
+// except --color, whose value is optional and so only given as --color=WHEN.
+// Anything that doesn't start with - is a positional argument. Patterns are given
+// with -e, which can be repeated, or else as the first positional argument; the
+// remaining positional arguments are the files to search.
+func parseArgs(args []string) (*options, error) {
+	opts := &options{backtrackLimit: regex.DefaultBacktrackLimit}
+	extended := false
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		// value returns the option's value, consuming the next argument if needed
+		value := func(name string) (string, error) {
+			if _, v, ok := strings.Cut(arg, "="); ok {
+				return v, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("option %s requires a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch {
+		case arg == "-E":
+			extended = true
+
+		case arg == "-e":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option -e requires a value")
+			}
+			i++
+			opts.patterns = append(opts.patterns, args[i])
+
+		case strings.HasPrefix(arg, "-e"):
+			opts.patterns = append(opts.patterns, arg[len("-e"):])
+
+		case arg == "--longest":
+			opts.longest = true
+
+		case arg == "--which":
+			opts.which = true
+
+		case arg == "-v":
+			opts.invert = true
+
+		case arg == "-o":
+			opts.only = true
+
+		case arg == "--color" || arg == "--colour":
+			// As in GNU grep, a bare --color means --color=auto
+			opts.color = colorAuto
+
+		case strings.HasPrefix(arg, "--color=") || strings.HasPrefix(arg, "--colour="):
+			_, v, _ := strings.Cut(arg, "=")
+			switch v {
+			case "auto":
+				opts.color = colorAuto
+			case "always":
+				opts.color = colorAlways
+			case "never":
+				opts.color = colorNever
+			default:
+				return nil, fmt.Errorf("invalid color mode: %q", v)
+			}
+
+		case arg == "-n":
+			opts.lineNumbers = true
+
+		case arg == "-b":
+			opts.byteOffset = true
+
+		case arg == "--column":
+			opts.column = true
+
+		case arg == "--vimgrep":
+			opts.vimgrep = true
+
+		case arg == "-c":
+			opts.count = true
+
+		case arg == "-l":
+			opts.list = listMatching
+
+		case arg == "-L":
+			opts.list = listNonMatching
+
+		case arg == "-q":
+			opts.quiet = true
+
+		case arg == "--crlf":
+			opts.crlf = true
+
+		case arg == "-r":
+			opts.recursive = true
+
+		case arg == "-R":
+			opts.recursive = true
+			opts.follow = true
+
+		case arg == "--hidden":
+			opts.hidden = true
+
+		case arg == "--no-ignore":
+			opts.noIgnore = true
+
+		case arg == "-H":
+			opts.names = namesAlways
+
+		case arg == "-h":
+			opts.names = namesNever
+
+		case arg == "--sort" || strings.HasPrefix(arg, "--sort="):
+			v, err := value("--sort")
+			if err != nil {
+				return nil, err
+			}
+			switch v {
+			case "path":
+				opts.unsorted = false
+			case "none":
+				opts.unsorted = true
+			default:
+				return nil, fmt.Errorf("invalid sort: %q", v)
+			}
+
+		case arg == "--timeout" || strings.HasPrefix(arg, "--timeout="):
+			v, err := value("--timeout")
+			if err != nil {
+				return nil, err
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid timeout: %q", v)
+			}
+			opts.timeout = d
+
+		case arg == "--backtrack-limit" || strings.HasPrefix(arg, "--backtrack-limit="):
+			v, err := value("--backtrack-limit")
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid backtrack limit: %q", v)
+			}
+			opts.backtrackLimit = n
+
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return nil, fmt.Errorf("unknown option: %s", arg)
+
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(opts.patterns) == 0 && len(positional) > 0 {
+		opts.patterns, positional = positional[:1], positional[1:]
+	}
+	if !extended || len(opts.patterns) == 0 {
+		return nil, errUsage
+	}
+	if opts.vimgrep {
+		// Editors need the name, line and column of every match to jump to
+		opts.lineNumbers, opts.column = true, true
+		if opts.names == namesAuto {
+			opts.names = namesAlways
+		}
+	}
+	if opts.only && opts.which {
+		return nil, fmt.Errorf("-o can't be combined with --which")
+	}
+	if opts.invert && opts.which {
+		return nil, fmt.Errorf("-v can't be combined with --which")
+	}
+	if len(positional) > 0 {
+		opts.files = positional
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    options
+		wantErr error
+	}{
+		{
+			name: "pattern only",
+			args: []string{"-E", "a+b"},
+			want: options{patterns: []string{"a+b"}, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "timeout with =",
+			args: []string{"-E", "--timeout=2s", "abc"},
+			want: options{patterns: []string{"abc"}, timeout: 2 * time.Second, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "options after the pattern",
+			args: []string{"-E", "abc", "--timeout", "150ms", "--backtrack-limit", "0"},
+			want: options{patterns: []string{"abc"}, timeout: 150 * time.Millisecond, backtrackLimit: 0},
+		},
+		{
+			name: "backtrack limit with =",
+			args: []string{"--backtrack-limit=1000", "-E", "abc"},
+			want: options{patterns: []string{"abc"}, backtrackLimit: 1000},
+		},
+		{
+			name: "leftmost-longest",
+			args: []string{"-E", "--longest", "abc"},
+			want: options{patterns: []string{"abc"}, backtrackLimit: regex.DefaultBacktrackLimit, longest: true},
+		},
+		{
+			name: "repeated -e",
+			args: []string{"-E", "-e", "a+", "-eb", "--which", "-e", "-c"},
+			want: options{patterns: []string{"a+", "b", "-c"}, which: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "CRLF line endings",
+			args: []string{"-E", "--crlf", "abc"},
+			want: options{patterns: []string{"abc"}, crlf: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "files after the pattern",
+			args: []string{"-E", "abc", "a.txt", "-", "-H"},
+			want: options{patterns: []string{"abc"}, files: []string{"a.txt", "-"}, names: namesAlways, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "recursive",
+			args: []string{"-E", "-r", "abc"},
+			want: options{patterns: []string{"abc"}, recursive: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "recursive following symlinks",
+			args: []string{"-E", "-R", "abc", "dir"},
+			want: options{patterns: []string{"abc"}, files: []string{"dir"}, recursive: true, follow: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "hidden files and no ignore files",
+			args: []string{"-E", "-r", "--hidden", "--no-ignore", "abc"},
+			want: options{patterns: []string{"abc"}, recursive: true, hidden: true, noIgnore: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "unsorted",
+			args: []string{"-E", "--sort", "none", "abc"},
+			want: options{patterns: []string{"abc"}, unsorted: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "last sort wins",
+			args: []string{"-E", "--sort=none", "--sort=path", "abc"},
+			want: options{patterns: []string{"abc"}, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "only matching",
+			args: []string{"-E", "-o", "abc"},
+			want: options{patterns: []string{"abc"}, only: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "color without a value",
+			args: []string{"-E", "--color", "abc"},
+			want: options{patterns: []string{"abc"}, color: colorAuto, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "color without a value overrides never",
+			args: []string{"-E", "--color=never", "--colour", "abc"},
+			want: options{patterns: []string{"abc"}, color: colorAuto, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "colour never",
+			args: []string{"-E", "--colour=never", "abc"},
+			want: options{patterns: []string{"abc"}, color: colorNever, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "invert",
+			args: []string{"-E", "-v", "abc"},
+			want: options{patterns: []string{"abc"}, invert: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "count, list and quiet",
+			args: []string{"-E", "-c", "-l", "-L", "-q", "abc"},
+			want: options{patterns: []string{"abc"}, count: true, list: listNonMatching, quiet: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "line numbers, byte offsets and columns",
+			args: []string{"-E", "-n", "-b", "--column", "abc"},
+			want: options{patterns: []string{"abc"}, lineNumbers: true, byteOffset: true, column: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "vimgrep implies names, line numbers and columns",
+			args: []string{"-E", "--vimgrep", "abc"},
+			want: options{patterns: []string{"abc"}, vimgrep: true, names: namesAlways, lineNumbers: true, column: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "vimgrep keeps -h",
+			args: []string{"-E", "-h", "--vimgrep", "abc"},
+			want: options{patterns: []string{"abc"}, vimgrep: true, names: namesNever, lineNumbers: true, column: true, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{
+			name: "files after -e",
+			args: []string{"-E", "-h", "-e", "a", "b.txt"},
+			want: options{patterns: []string{"a"}, files: []string{"b.txt"}, names: namesNever, backtrackLimit: regex.DefaultBacktrackLimit},
+		},
+		{name: "-e without a value", args: []string{"-E", "-e"}},
+		{name: "missing -E", args: []string{"abc"}, wantErr: errUsage},
+		{name: "missing pattern", args: []string{"-E"}, wantErr: errUsage},
+		{name: "invalid timeout", args: []string{"-E", "a", "--timeout=soon"}},
+		{name: "negative limit", args: []string{"-E", "a", "--backtrack-limit=-1"}},
+		{name: "-o with --which", args: []string{"-E", "-o", "--which", "a"}},
+		{name: "invalid color mode", args: []string{"-E", "a", "--color=sometimes"}},
+		{name: "-v with --which", args: []string{"-E", "-v", "--which", "a"}},
+		{name: "invalid sort", args: []string{"-E", "a", "--sort=size"}},
+		{name: "missing value", args: []string{"-E", "a", "--timeout"}},
+		{name: "unknown option", args: []string{"-E", "a", "-Z"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseArgs(tt.args)
+			wantErr := tt.wantErr != nil || len(tt.want.patterns) == 0
+			if (err != nil) != wantErr {
+				t.Fatalf("parseArgs() error = %v, wantErr %v", err, wantErr)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseArgs() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parseArgs() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
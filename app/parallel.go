@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// task is the search of one file, or a message met while walking directories,
+// which takes the place of a file in the output.
+type task struct {
+	path    string        // the file to search; empty for a walk message
+	out     output        // the lines to write to stdout
+	msg     string        // the message to write to stderr after them
+	matched bool          // a line was selected
+	failed  bool          // an error occurred
+	aborted bool          // the search ran out of backtracking steps or time
+	done    chan struct{} // closed once the task is finished
+}
+
+// maxHeld is how much output a task holds in memory while it waits for its turn
+// to be written. The rest goes to a temporary file.
+const maxHeld = 1 << 20
+
+// output is where a task writes its lines. Until the task reaches the head of
+// the output order they are held, in memory up to maxHeld bytes and in a
+// temporary file past that. Once it is at the head, the held lines are written
+// to stdout and the following ones go straight there, so a long search streams
+// its output instead of holding all of it.
+type output struct {
+	mu    sync.Mutex
+	w     *bufio.Writer // stdout once the task is at the head; nil until then
+	held  bytes.Buffer
+	spill *os.File // holds the output past maxHeld bytes, if any
+	wait  bool     // the search is waiting for input
+}
+
+// Write writes p to stdout if the task is at the head, and holds it otherwise.
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.w != nil {
+		return o.w.Write(p)
+	}
+	if o.spill == nil && o.held.Len()+len(p) > maxHeld {
+		f, err := os.CreateTemp("", "mygrep-")
+		if err != nil {
+			return 0, err
+		}
+		o.spill = f
+	}
+	if o.spill != nil {
+		return o.spill.Write(p)
+	}
+	return o.held.Write(p)
+}
+
+// waiting records whether the search is waiting for input. While it waits, the
+// lines it wrote so far are flushed to stdout once the task is at the head.
+func (o *output) waiting(on bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.wait = on
+	if !on || o.w == nil {
+		return nil
+	}
+	return o.w.Flush()
+}
+
+// release writes the held output to w, which is stdout, and makes the task's
+// output go straight there from then on.
+func (o *output) release(w *bufio.Writer) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w = w
+	_, err := w.Write(o.held.Bytes())
+	o.held = bytes.Buffer{}
+	if o.spill != nil {
+		if err == nil {
+			err = o.copySpill(w)
+		}
+		o.spill.Close()
+		os.Remove(o.spill.Name())
+		o.spill = nil
+	}
+	if err == nil && o.wait {
+		err = w.Flush()
+	}
+	return err
+}
+
+// copySpill writes the output held in the temporary file to w.
+func (o *output) copySpill(w io.Writer) error {
+	if _, err := o.spill.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, o.spill)
+	return err
+}
+
+// flushingReader reads the input of a task from r and tells its output when a
+// read may wait, so that the output of the lines read so far isn't held back
+// by a slow stream.
+type flushingReader struct {
+	r   io.Reader
+	out *output
+}
+
+func (fr *flushingReader) Read(p []byte) (int, error) {
+	if err := fr.out.waiting(true); err != nil {
+		return 0, err
+	}
+	defer fr.out.waiting(false)
+	return fr.r.Read(p)
+}
+
+// scheduler runs searches on a pool of workers, so that many files are searched
+// at once. Every file is searched into an output of its own, which keeps its
+// lines together however the workers interleave. The outputs are written as
+// the searches finish or, when sorted is set, in the order the files were
+// found, with the search at the head of that order writing as it goes.
+//
+// The workers share the compiled pattern: Regexp and RegexSet are safe for
+// concurrent use and keep their per-search state in pools.
+type scheduler struct {
+	workers int
+	sorted  bool
+	search  func(t *task)   // runs t in a worker
+	done    <-chan struct{} // closed to stop early; may be nil
+}
+
+// run calls produce, which adds the tasks to run, and emit with every finished
+// task. Before that, head is called with the task once it is the next one to be
+// emitted: as soon as the tasks before it are emitted when sorted is set, and
+// right before emit otherwise. head and emit are called from the goroutine that
+// called run, one task at a time.
+//
+// Once done is closed, run returns without waiting for the searches still
+// running, which may be blocked reading their input, and add drops the tasks
+// it is given.
+func (s *scheduler) run(produce func(add func(*task)), head, emit func(*task)) {
+	// sorted mode emits from ordered, which holds the tasks in the order they
+	// were added, and the other from finished. Both hold a few tasks per worker
+	// so that the workers don't wait on a slow file, without reading ahead of
+	// the output without bound.
+	jobs := make(chan *task, s.workers)
+	ordered := make(chan *task, 4*s.workers)
+	finished := make(chan *task, s.workers)
+
+	// work runs t and reports whether the scheduler is still running
+	work := func(t *task) bool {
+		s.search(t)
+		close(t.done)
+		return s.sorted || s.send(finished, t)
+	}
+
+	var wg sync.WaitGroup
+	for range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				if !work(t) {
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		produce(func(t *task) {
+			t.done = make(chan struct{})
+			if s.sorted && !s.send(ordered, t) {
+				return
+			}
+			switch {
+			case t.path == "-":
+				// Standard input may keep a search waiting for as long as its
+				// writer likes, so it doesn't take one of the workers
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					work(t)
+				}()
+			case t.path != "":
+				s.send(jobs, t)
+			case s.sorted:
+				close(t.done)
+			default:
+				close(t.done)
+				s.send(finished, t)
+			}
+		})
+		close(jobs)
+		close(ordered)
+	}()
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	tasks := finished
+	if s.sorted {
+		tasks = ordered
+	}
+	for {
+		var t *task
+		var ok bool
+		select {
+		case t, ok = <-tasks:
+		case <-s.done:
+			return
+		}
+		if !ok {
+			return
+		}
+		head(t)
+		select {
+		case <-t.done:
+		case <-s.done:
+			return
+		}
+		emit(t)
+	}
+}
+
+// send sends t on ch and reports whether it was sent before done was closed.
+func (s *scheduler) send(ch chan<- *task, t *task) bool {
+	select {
+	case ch <- t:
+		return true
+	case <-s.done:
+		return false
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutputSpill(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	var o output
+	line := strings.Repeat("x", 1000) + "\n"
+	var want strings.Builder
+	for want.Len() < 3*maxHeld {
+		o.Write([]byte(line))
+		want.WriteString(line)
+	}
+	if o.held.Len() > maxHeld {
+		t.Errorf("holding %d bytes in memory, want at most %d", o.held.Len(), maxHeld)
+	}
+	if o.spill == nil {
+		t.Fatal("no temporary file for the output past maxHeld")
+	}
+	name := o.spill.Name()
+
+	var stdout bytes.Buffer
+	w := bufio.NewWriter(&stdout)
+	if err := o.release(w); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	// Once released, the output goes straight to stdout
+	o.Write([]byte("last\n"))
+	want.WriteString("last\n")
+	w.Flush()
+	if stdout.String() != want.String() {
+		t.Errorf("stdout holds %d bytes, want %d", stdout.Len(), want.Len())
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s still exists: %v", name, err)
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// matcher decides whether a line matches. *regex.Regexp and *regex.RegexSet
+// both implement it.
+type matcher interface {
+	Match(line []byte) (bool, error)
+}
+
+// spanMatcher is a matcher that can also locate the matches in a line, which
+// -o, --color and --column need. *regex.Regexp and alternation implement it.
+type spanMatcher interface {
+	matcher
+	ForEachMatch(line []byte, fn func(loc []int) bool) error
+}
+
+// compile builds the matcher for the command line. A single pattern becomes a
+// Regexp; several patterns, or --which, become a RegexSet, so that every line
+// is searched for all of them in one pass. A RegexSet can't locate its
+// matches, so with -o, --color or --column several patterns become an
+// alternation instead, unless no match is shown: the lines -v selects have
+// none, and -c, -l, -L and -q show no lines.
+func compile(opts *options) (matcher, error) {
+	var deadline time.Time
+	if opts.timeout > 0 {
+		deadline = time.Now().Add(opts.timeout)
+	}
+
+	spans := (opts.only || opts.color == colorAlways || opts.column) && !opts.invert && !opts.summarizes()
+	if len(opts.patterns) > 1 && spans && !opts.which {
+		var alt alternation
+		for _, expr := range opts.patterns {
+			re, err := compileRegexp(expr, opts, deadline)
+			if err != nil {
+				return nil, err
+			}
+			alt = append(alt, re)
+		}
+		return alt, nil
+	}
+
+	if len(opts.patterns) > 1 || opts.which {
+		set, err := regex.CompileSet(opts.patterns)
+		if err != nil {
+			return nil, err
+		}
+		set.SetDeadline(deadline)
+		return set, nil
+	}
+
+	return compileRegexp(opts.patterns[0], opts, deadline)
+}
+
+// compileRegexp compiles a single pattern with the limits of the command line.
+func compileRegexp(expr string, opts *options, deadline time.Time) (*regex.Regexp, error) {
+	re, err := regex.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	re.SetBacktrackLimit(opts.backtrackLimit)
+	if opts.longest {
+		re.Longest()
+	}
+	re.SetDeadline(deadline)
+	return re, nil
+}
+
+// alternation matches any of several patterns, like a RegexSet, but can also
+// locate the matches for -o, --color and --column.
+type alternation []*regex.Regexp
+
+// Match reports whether any of the patterns matches line.
+func (a alternation) Match(line []byte) (bool, error) {
+	for _, re := range a {
+		if ok, err := re.Match(line); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// ForEachMatch calls fn with the location of every successive non-overlapping
+// match of any of the patterns in line, until fn returns false. Where several
+// patterns match, the leftmost match wins, and the longest among those starting
+// at the same offset. Empty matches are handled as by regex.Regexp.ForEachMatch.
+func (a alternation) ForEachMatch(line []byte, fn func(loc []int) bool) error {
+	finders := make([]*regex.Finder, len(a))
+	// next holds the leftmost match of each pattern found so far, which stays
+	// the leftmost one from pos on for as long as it doesn't start before pos;
+	// done marks the patterns that have no match left
+	next := make([][]int, len(a))
+	done := make([]bool, len(a))
+	for i, re := range a {
+		finders[i] = re.NewFinder(line)
+	}
+
+	prevEnd := -1
+	for pos := 0; pos <= len(line); {
+		var best []int
+		for i, f := range finders {
+			if done[i] {
+				continue
+			}
+			if next[i] == nil || next[i][0] < pos {
+				loc, err := f.FindIndexAt(pos)
+				if err != nil {
+					return err
+				}
+				if loc == nil {
+					done[i] = true
+					continue
+				}
+				next[i] = loc
+			}
+			loc := next[i]
+			if best == nil || loc[0] < best[0] || loc[0] == best[0] && loc[1] > best[1] {
+				best = loc
+			}
+		}
+		if best == nil {
+			return nil
+		}
+		loc := best
+
+		accept := true
+		if loc[1] == loc[0] {
+			// Empty match: step over the next rune so the search makes progress
+			if loc[0] == prevEnd {
+				accept = false
+			}
+			if loc[1] < len(line) {
+				_, width := utf8.DecodeRune(line[loc[1]:])
+				pos = loc[1] + width
+			} else {
+				pos = loc[1] + 1
+			}
+		} else {
+			pos = loc[1]
+		}
+		prevEnd = loc[1]
+
+		if accept && !fn(loc) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// searcher runs the search described by the command line over one input at a
+// time, writing the results to out.
+type searcher struct {
+	m        matcher
+	opts     *options
+	out      io.Writer
+	withName bool            // prefix every output line with the name of its input
+	colors   *colors         // how to color the output; nil for no color
+	done     <-chan struct{} // closed when -q has found a line elsewhere; may be nil
+	buf      []byte          // output being assembled for the current line
+	spans    []int           // start and end offsets of the non-empty matches in the current line
+}
+
+// search writes the lines read from r that match, or with --which the indices
+// of the patterns matching each of them, or with -o the matched parts of them,
+// and reports whether any line was selected. With -v the lines that don't
+// match are selected instead, and with -c, -l, -L or -q the input is only
+// summarized. The input is read in chunks, so it can be much larger than
+// memory. name is the name of the input, shown in front of its lines when
+// withName is set.
+func (s *searcher) search(r io.Reader, name string) (bool, error) {
+	if s.opts.summarizes() {
+		return s.summarize(r, name)
+	}
+
+	matched := false
+	lr := regex.NewLineReader(r)
+	lr.SetCRLF(s.opts.crlf)
+	for lr.Next() {
+		line := lr.Line()
+		s.buf = s.buf[:0]
+
+		var ok bool
+		var err error
+		switch {
+		case s.opts.which:
+			ok, err = s.appendWhich(name, line)
+		case s.opts.invert:
+			ok, err = s.appendInverted(name, line)
+		case s.opts.only || s.opts.vimgrep:
+			ok, err = s.appendMatches(name, line)
+		case s.colors != nil || s.opts.column:
+			ok, err = s.appendHighlighted(name, line)
+		default:
+			if ok, err = s.m.Match(line.Text); ok {
+				s.appendPrefix(name, line, -1)
+				s.buf = appendLine(s.buf, line)
+			}
+		}
+		if err != nil {
+			return matched, err
+		}
+		if !ok {
+			continue
+		}
+
+		matched = true
+		if _, err := s.out.Write(s.buf); err != nil {
+			return matched, err
+		}
+	}
+	return matched, lr.Err()
+}
+
+// summarize reads the lines from r for -c, -l, -L and -q, which report on the
+// input as a whole, and reports whether any line was selected. All but -c stop
+// reading at the first selected line, and -q also once done is closed.
+func (s *searcher) summarize(r io.Reader, name string) (bool, error) {
+	first := s.opts.list != listNone || s.opts.quiet
+	count := 0
+	lr := regex.NewLineReader(r)
+	lr.SetCRLF(s.opts.crlf)
+	for lr.Next() {
+		select {
+		case <-s.done:
+			return count > 0, nil
+		default:
+		}
+
+		// The selection is all that counts, so the cheapest test will do
+		ok, err := s.m.Match(lr.Line().Text)
+		if err != nil {
+			return count > 0, err
+		}
+		if ok != s.opts.invert {
+			count++
+			if first {
+				break
+			}
+		}
+	}
+	if err := lr.Err(); err != nil {
+		return count > 0, err
+	}
+
+	c := s.colors
+	s.buf = s.buf[:0]
+	switch {
+	case s.opts.quiet:
+	case s.opts.list == listMatching && count > 0, s.opts.list == listNonMatching && count == 0:
+		s.buf = c.start(s.buf, colorFileName)
+		s.buf = append(s.buf, name...)
+		s.buf = c.end(s.buf, colorFileName)
+		s.buf = append(s.buf, '\n')
+	case s.opts.list == listNone:
+		if s.withName {
+			s.appendName(name)
+		}
+		s.buf = strconv.AppendInt(s.buf, int64(count), 10)
+		s.buf = append(s.buf, '\n')
+	}
+	_, err := s.out.Write(s.buf)
+	return count > 0, err
+}
+
+// appendPrefix appends the fields shown in front of an output line, each
+// followed by a separator: the name of the input, the line number, the column
+// and the byte offset, as enabled. start is the offset in the line of the
+// match the output is about, or -1 if there is none. The column is that of the
+// match, or 1 without one; the byte offset is that of the line, or with -o of
+// the match.
+func (s *searcher) appendPrefix(name string, line regex.Line, start int) {
+	if s.withName {
+		s.appendName(name)
+	}
+	if s.opts.lineNumbers {
+		s.appendNumber(colorLineNum, int64(line.Number))
+	}
+	if s.opts.column {
+		s.appendNumber(colorLineNum, int64(max(start, 0)+1))
+	}
+	if s.opts.byteOffset {
+		offset := line.Offset
+		if s.opts.only && start >= 0 {
+			offset += int64(start)
+		}
+		s.appendNumber(colorByteOff, offset)
+	}
+}
+
+// appendName appends the name of the input in front of a line, followed by a
+// separator.
+func (s *searcher) appendName(name string) {
+	c := s.colors
+	s.buf = c.start(s.buf, colorFileName)
+	s.buf = append(s.buf, name...)
+	s.buf = c.end(s.buf, colorFileName)
+	s.appendSeparator()
+}
+
+// appendNumber appends a number in the color of part, followed by a separator.
+func (s *searcher) appendNumber(part colorPart, n int64) {
+	c := s.colors
+	s.buf = c.start(s.buf, part)
+	s.buf = strconv.AppendInt(s.buf, n, 10)
+	s.buf = c.end(s.buf, part)
+	s.appendSeparator()
+}
+
+// appendSeparator appends the separator that ends a field of the prefix.
+func (s *searcher) appendSeparator() {
+	c := s.colors
+	s.buf = c.start(s.buf, colorSeparator)
+	s.buf = append(s.buf, ':')
+	s.buf = c.end(s.buf, colorSeparator)
+}
+
+// appendWhich appends the indices of the patterns that match line, separated
+// by commas, and reports whether there were any.
+func (s *searcher) appendWhich(name string, line regex.Line) (bool, error) {
+	ids, err := s.m.(*regex.RegexSet).Matches(line.Text)
+	if ids == nil || err != nil {
+		return false, err
+	}
+	s.appendPrefix(name, line, -1)
+	for i, id := range ids {
+		if i > 0 {
+			s.buf = append(s.buf, ',')
+		}
+		s.buf = strconv.AppendInt(s.buf, int64(id), 10)
+	}
+	s.buf = append(s.buf, '\n')
+	return true, nil
+}
+
+// appendInverted appends the line if it doesn't match, for -v, and reports
+// whether it was selected. With -o a selected line prints nothing, since it
+// holds no match to print.
+func (s *searcher) appendInverted(name string, line regex.Line) (bool, error) {
+	ok, err := s.m.Match(line.Text)
+	if ok || err != nil {
+		return false, err
+	}
+	if s.opts.only {
+		return true, nil
+	}
+	s.appendPrefix(name, line, -1)
+	s.buf = s.colors.appendText(s.buf, colorSelected, line.Text)
+	s.buf = appendTerminator(s.buf, line)
+	return true, nil
+}
+
+// findSpans collects the non-empty matches in text into s.spans and returns
+// the start of the first match, empty or not, or -1 if there is none.
+func (s *searcher) findSpans(text []byte) (int, error) {
+	s.spans = s.spans[:0]
+	first := -1
+	err := s.m.(spanMatcher).ForEachMatch(text, func(loc []int) bool {
+		if first < 0 {
+			first = loc[0]
+		}
+		if loc[0] < loc[1] {
+			s.spans = append(s.spans, loc[0], loc[1])
+		}
+		return true
+	})
+	return first, err
+}
+
+// appendMatches appends an output line for every non-empty match in line and
+// reports whether the line matched at all. With -o the output line holds the
+// match, and with --vimgrep the whole line.
+//
+// As with GNU grep, -o prints nothing for a line whose only matches are empty,
+// though the line is selected. --vimgrep prints such a line once, at its first
+// match, so that every selected line can be jumped to.
+func (s *searcher) appendMatches(name string, line regex.Line) (bool, error) {
+	first, err := s.findSpans(line.Text)
+	if first < 0 || err != nil {
+		return false, err
+	}
+	if len(s.spans) == 0 && !s.opts.only {
+		s.appendPrefix(name, line, first)
+		s.appendSpans(line.Text)
+		s.buf = appendTerminator(s.buf, line)
+	}
+	for i := 0; i < len(s.spans); i += 2 {
+		start, end := s.spans[i], s.spans[i+1]
+		s.appendPrefix(name, line, start)
+		if s.opts.only {
+			s.buf = s.colors.appendText(s.buf, colorMatch, line.Text[start:end])
+			s.buf = append(s.buf, '\n')
+		} else {
+			s.appendSpans(line.Text)
+			s.buf = appendTerminator(s.buf, line)
+		}
+	}
+	return true, nil
+}
+
+// appendHighlighted appends the line with every non-empty match colored, and
+// reports whether the line matched.
+func (s *searcher) appendHighlighted(name string, line regex.Line) (bool, error) {
+	first, err := s.findSpans(line.Text)
+	if first < 0 || err != nil {
+		return false, err
+	}
+	s.appendPrefix(name, line, first)
+	s.appendSpans(line.Text)
+	s.buf = appendTerminator(s.buf, line)
+	return true, nil
+}
+
+// appendSpans appends text with the matches in s.spans colored.
+func (s *searcher) appendSpans(text []byte) {
+	c := s.colors
+	s.buf = c.start(s.buf, colorSelected)
+	last := 0
+	for i := 0; i < len(s.spans); i += 2 {
+		start, end := s.spans[i], s.spans[i+1]
+		s.buf = append(s.buf, text[last:start]...)
+		s.buf = c.appendText(s.buf, colorMatch, text[start:end])
+		// the color of the rest of the line was reset along with the match
+		s.buf = c.start(s.buf, colorSelected)
+		last = end
+	}
+	s.buf = append(s.buf, text[last:]...)
+	s.buf = c.end(s.buf, colorSelected)
+}
+
+// appendLine appends the line as it was read, terminator included. A last line
+// without a terminator gets a newline.
+func appendLine(buf []byte, line regex.Line) []byte {
+	buf = append(buf, line.Text...)
+	return appendTerminator(buf, line)
+}
+
+// appendTerminator appends the terminator of line, or a newline if it has none.
+func appendTerminator(buf []byte, line regex.Line) []byte {
+	if len(line.Terminator) == 0 {
+		return append(buf, '\n')
+	}
+	return append(buf, line.Terminator...)
+}
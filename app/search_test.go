@@ -0,0 +1,370 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// runSearch parses args, searches input and returns what was written and
+// whether any line matched.
+func runSearch(t *testing.T, args []string, input string) (string, bool) {
+	t.Helper()
+	opts, err := parseArgs(args)
+	if err != nil {
+		t.Fatalf("parseArgs(%q) error = %v", args, err)
+	}
+	m, err := compile(opts)
+	if err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	var out strings.Builder
+	s := &searcher{m: m, opts: opts, out: &out, withName: opts.names == namesAlways}
+	if opts.color == colorAlways {
+		s.colors = parseColors("")
+	}
+	matched, err := s.search(strings.NewReader(input), stdinName)
+	if err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	return out.String(), matched
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		input       string
+		want        string
+		wantMatched bool
+	}{
+		{
+			name:        "prints matching lines",
+			args:        []string{"-E", "^b+$"},
+			input:       "abc\nbbb\nb\n",
+			want:        "bbb\nb\n",
+			wantMatched: true,
+		},
+		{
+			name:  "no line matches",
+			args:  []string{"-E", "^b+$"},
+			input: "abc\nbba\n",
+		},
+		{
+			name:        "last line without newline",
+			args:        []string{"-E", "c"},
+			input:       "abc\nxyc",
+			want:        "abc\nxyc\n",
+			wantMatched: true,
+		},
+		{
+			name:        "any pattern of several",
+			args:        []string{"-E", "-e", "x", "-e", "\\d"},
+			input:       "abc\na1\nx\n",
+			want:        "a1\nx\n",
+			wantMatched: true,
+		},
+		{
+			name:  "empty input",
+			args:  []string{"-E", "^$"},
+			input: "",
+		},
+		{
+			name:        "empty lines",
+			args:        []string{"-E", "^$"},
+			input:       "a\n\nb\n\n",
+			want:        "\n\n",
+			wantMatched: true,
+		},
+		{
+			name:  "carriage return is part of the line",
+			args:  []string{"-E", "a$"},
+			input: "a\r\nba\r\n",
+		},
+		{
+			name:        "CRLF line endings",
+			args:        []string{"-E", "--crlf", "a$"},
+			input:       "a\r\nab\r\nba\n",
+			want:        "a\r\nba\n",
+			wantMatched: true,
+		},
+		{
+			name:        "which",
+			args:        []string{"-E", "--which", "-e", "error", "-e", "\\d+", "-e", "^warn"},
+			input:       "warn: disk 90% full\nok\nerror 42\nwarning\n",
+			want:        "1,2\n0,1\n2\n",
+			wantMatched: true,
+		},
+		{
+			name:        "only matching",
+			args:        []string{"-E", "-o", "\\d+"},
+			input:       "a1b22c333\nnone\n4\n",
+			want:        "1\n22\n333\n4\n",
+			wantMatched: true,
+		},
+		{
+			name:        "only matching with a name",
+			args:        []string{"-E", "-o", "-H", "ab"},
+			input:       "abxab\r\n",
+			want:        "(standard input):ab\n(standard input):ab\n",
+			wantMatched: true,
+		},
+		{
+			name:        "only matching skips empty matches",
+			args:        []string{"-E", "-o", "a?"},
+			input:       "bab\nbbb\n",
+			want:        "a\n",
+			wantMatched: true,
+		},
+		{
+			name:        "only matching several patterns",
+			args:        []string{"-E", "-o", "-e", "ab", "-e", "abc", "-e", "^x", "-e", "\\d$"},
+			input:       "xabcab x1\nabxx\n",
+			want:        "x\nabc\nab\n1\nab\n",
+			wantMatched: true,
+		},
+		{
+			name:        "invert",
+			args:        []string{"-E", "-v", "^a"},
+			input:       "abc\nbcd\naaa\ncde",
+			want:        "bcd\ncde\n",
+			wantMatched: true,
+		},
+		{
+			name:  "invert with every line matching",
+			args:  []string{"-E", "-v", "\\w"},
+			input: "abc\nb\n",
+		},
+		{
+			name:        "invert several patterns",
+			args:        []string{"-E", "-v", "-e", "a", "-e", "b", "--color=always"},
+			input:       "a\nb\nc\n",
+			want:        "c\n",
+			wantMatched: true,
+		},
+		{
+			name:        "invert with only matching prints nothing",
+			args:        []string{"-E", "-v", "-o", "-H", "a"},
+			input:       "abc\nxyz\n",
+			wantMatched: true,
+		},
+		{
+			name:        "count",
+			args:        []string{"-E", "-c", "-o", "a"},
+			input:       "aa\nb\na\n",
+			want:        "2\n",
+			wantMatched: true,
+		},
+		{
+			name:  "count without a match",
+			args:  []string{"-E", "-c", "-H", "x"},
+			input: "aa\nb\n",
+			want:  "(standard input):0\n",
+		},
+		{
+			name:        "count inverted",
+			args:        []string{"-E", "-c", "-v", "a"},
+			input:       "aa\nb\na\nc",
+			want:        "2\n",
+			wantMatched: true,
+		},
+		{
+			name:        "list files with matches",
+			args:        []string{"-E", "-l", "-c", "a"},
+			input:       "b\na\na\n",
+			want:        "(standard input)\n",
+			wantMatched: true,
+		},
+		{
+			name:  "list files with matches, none",
+			args:  []string{"-E", "-l", "x"},
+			input: "b\na\n",
+		},
+		{
+			name:  "list files without matches",
+			args:  []string{"-E", "-L", "x"},
+			input: "b\na\n",
+			want:  "(standard input)\n",
+		},
+		{
+			name:        "list files without matches, with a match",
+			args:        []string{"-E", "-L", "a"},
+			input:       "b\na\n",
+			wantMatched: true,
+		},
+		{
+			name:        "list files with non-matching lines",
+			args:        []string{"-E", "-l", "-v", "a"},
+			input:       "a\nb\n",
+			want:        "(standard input)\n",
+			wantMatched: true,
+		},
+		{
+			name:        "quiet",
+			args:        []string{"-E", "-q", "-l", "a"},
+			input:       "b\na\n",
+			wantMatched: true,
+		},
+		{
+			name:        "line numbers",
+			args:        []string{"-E", "-n", "b"},
+			input:       "abc\nxyz\nbb\n",
+			want:        "1:abc\n3:bb\n",
+			wantMatched: true,
+		},
+		{
+			name:        "byte offsets of lines",
+			args:        []string{"-E", "-b", "-H", "b"},
+			input:       "abc\nxyz\nbb\n",
+			want:        "(standard input):0:abc\n(standard input):8:bb\n",
+			wantMatched: true,
+		},
+		{
+			name:        "byte offsets of matches",
+			args:        []string{"-E", "-b", "-n", "-o", "b"},
+			input:       "abc\nxyz\nbb\n",
+			want:        "1:1:b\n3:8:b\n3:9:b\n",
+			wantMatched: true,
+		},
+		{
+			name:        "column of the first match",
+			args:        []string{"-E", "--column", "-n", "-e", "z", "-e", "y"},
+			input:       "abc\nxyz\nzz\n",
+			want:        "2:2:xyz\n3:1:zz\n",
+			wantMatched: true,
+		},
+		{
+			name:        "column of each match with -o",
+			args:        []string{"-E", "--column", "-o", "\\d+"},
+			input:       "a1 22\n",
+			want:        "2:1\n4:22\n",
+			wantMatched: true,
+		},
+		{
+			name:        "column of lines selected by -v",
+			args:        []string{"-E", "--column", "-v", "a"},
+			input:       "a\nb\n",
+			want:        "1:b\n",
+			wantMatched: true,
+		},
+		{
+			name:        "vimgrep",
+			args:        []string{"-E", "--vimgrep", "o"},
+			input:       "foo\nbar\nno\n",
+			want:        "(standard input):1:2:foo\n(standard input):1:3:foo\n(standard input):3:2:no\n",
+			wantMatched: true,
+		},
+		{
+			name:        "vimgrep with empty matches only",
+			args:        []string{"-E", "--vimgrep", "-h", "x?$"},
+			input:       "ab\n",
+			want:        "1:3:ab\n",
+			wantMatched: true,
+		},
+		{
+			name:        "vimgrep with color",
+			args:        []string{"-E", "--vimgrep", "-h", "--color=always", "b"},
+			input:       "abc\n",
+			want:        "\x1b[32m\x1b[K1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K\x1b[32m\x1b[K2\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K" + "a\x1b[01;31m\x1b[Kb\x1b[m\x1b[Kc\n",
+			wantMatched: true,
+		},
+		{
+			name:        "color highlights every match",
+			args:        []string{"-E", "--color=always", "-H", "a\\d"},
+			input:       "xa1ya2z\nnone\n",
+			want:        "\x1b[35m\x1b[K(standard input)\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[K" + "x\x1b[01;31m\x1b[Ka1\x1b[m\x1b[Ky\x1b[01;31m\x1b[Ka2\x1b[m\x1b[Kz\n",
+			wantMatched: true,
+		},
+		{
+			name:        "color skips empty matches",
+			args:        []string{"-E", "--color=always", "b?"},
+			input:       "abc\nxyz\n",
+			want:        "a\x1b[01;31m\x1b[Kb\x1b[m\x1b[Kc\nxyz\n",
+			wantMatched: true,
+		},
+		{
+			name:        "color with several patterns",
+			args:        []string{"-E", "--color=always", "-e", "ab", "-e", "c"},
+			input:       "abxc\r\n",
+			want:        "\x1b[01;31m\x1b[Kab\x1b[m\x1b[Kx\x1b[01;31m\x1b[Kc\x1b[m\x1b[K\r\n",
+			wantMatched: true,
+		},
+		{
+			name:        "color with only matching",
+			args:        []string{"-E", "--color=always", "-o", "\\d"},
+			input:       "a1b2\n",
+			want:        "\x1b[01;31m\x1b[K1\x1b[m\x1b[K\n\x1b[01;31m\x1b[K2\x1b[m\x1b[K\n",
+			wantMatched: true,
+		},
+		{
+			name:        "only matching several patterns, empty matches only",
+			args:        []string{"-E", "-o", "-e", "z?", "-e", "^$"},
+			input:       "abc\n",
+			wantMatched: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched := runSearch(t, tt.args, tt.input)
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			if matched != tt.wantMatched {
+				t.Errorf("matched = %v, want %v", matched, tt.wantMatched)
+			}
+		})
+	}
+}
+
+// failingReader returns its error on every read.
+type failingReader struct{ err error }
+
+func (r failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestSearchStopsAtFirstLine(t *testing.T) {
+	errRead := errors.New("read past the first match")
+	for _, args := range [][]string{
+		{"-E", "-l", "a"},
+		{"-E", "-L", "a"},
+		{"-E", "-q", "a"},
+		{"-E", "-l", "-v", "x"},
+	} {
+		opts, err := parseArgs(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := compile(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &searcher{m: m, opts: opts, out: io.Discard}
+		r := io.MultiReader(strings.NewReader("abc\n"), failingReader{errRead})
+		if matched, err := s.search(r, stdinName); !matched || err != nil {
+			t.Errorf("%q: search() = %v, %v; want true, nil", args, matched, err)
+		}
+	}
+}
+
+func TestAlternationLongLine(t *testing.T) {
+	// Every pattern used to search the rest of the line after every match,
+	// which took quadratic time
+	line := strings.Repeat("ab ", 400_000)
+	start := time.Now()
+	a := alternation{regex.MustCompile("a"), regex.MustCompile("b"), regex.MustCompile("^x")}
+	n := 0
+	err := a.ForEachMatch([]byte(line), func(loc []int) bool {
+		n++
+		return true
+	})
+	if err != nil || n != 800_000 {
+		t.Errorf("ForEachMatch() found %d matches, error %v; want %d", n, err, 800_000)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("searching a %d-byte line took %v", len(line), elapsed)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import "syscall"
+
+// ioctlGetTermios is the ioctl request that reads the attributes of a terminal.
+const ioctlGetTermios = syscall.TIOCGETA
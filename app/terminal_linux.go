@@ -0,0 +1,6 @@
+package main
+
+import "syscall"
+
+// ioctlGetTermios is the ioctl request that reads the attributes of a terminal.
+const ioctlGetTermios = syscall.TCGETS
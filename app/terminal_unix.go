@@ -0,0 +1,25 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal reports whether f is a terminal, by asking for its terminal
+// attributes, which only a terminal has. Other character devices, such as
+// /dev/null, aren't terminals.
+func isTerminal(f *os.File) bool {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		var attrs syscall.Termios
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&attrs)))
+	})
+	return err == nil && errno == 0
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// walker finds the files to search under the directories given to -r and -R.
+//
+// Directory entries are visited in lexical order, so the output is the same from
+// one run to the next. Only regular files are searched; devices, FIFOs and
+// sockets met along the way are skipped. Symbolic links named on the command
+// line are always followed, those found inside directories only with -R.
+//
+// Unless told otherwise, hidden files and directories are skipped, and so are
+// the paths excluded by the .gitignore and .ignore files of the directories
+// being walked and of those above them, up to the root of the repository.
+type walker struct {
+	follow   bool              // -R: follow symbolic links inside directories
+	hidden   bool              // --hidden: don't skip hidden files and directories
+	noIgnore bool              // --no-ignore: don't read ignore files
+	done     <-chan struct{}   // closed to stop the walk early; may be nil
+	onFile   func(path string) // called for every file to search
+	onError  func(err error)   // called for every file or directory that can't be read
+	onLoop   func(path string) // called for every directory that contains itself
+}
+
+// walk visits root, which is the current directory if empty. A root that isn't
+// a directory is passed to onFile whatever its type, as if it had been named
+// without -r.
+func (w *walker) walk(root string) {
+	info, err := os.Stat(osPath(root))
+	if err != nil {
+		w.onError(err)
+		return
+	}
+	if !info.IsDir() {
+		w.onFile(root)
+		return
+	}
+
+	var ignores ignoreStack
+	if !w.noIgnore {
+		// Ignore files higher up in the repository apply below root as well
+		if ignores, err = ignores.loadAncestors(root); err != nil {
+			w.onError(err)
+		}
+	}
+	w.walkDir(root, info, nil, ignores)
+}
+
+// walkDir visits the entries of dir. ancestors holds the directories on the way
+// from the root down to dir, which a symbolic link may lead back to, and
+// ignores the ignore files found in them.
+func (w *walker) walkDir(dir string, info fs.FileInfo, ancestors []fs.FileInfo, ignores ignoreStack) {
+	for _, a := range ancestors {
+		if os.SameFile(a, info) {
+			w.onLoop(dir)
+			return
+		}
+	}
+	ancestors = append(ancestors, info)
+
+	if !w.noIgnore {
+		var err error
+		if ignores, err = ignores.load(dir); err != nil {
+			w.onError(err)
+		}
+	}
+
+	entries, err := os.ReadDir(osPath(dir))
+	if err != nil {
+		// ReadDir still returns the entries it read before the error
+		w.onError(err)
+	}
+	for _, entry := range entries {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+		if !w.hidden && isHidden(entry.Name()) {
+			continue
+		}
+		path := joinPath(dir, entry.Name())
+
+		var info fs.FileInfo
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if !w.follow {
+				continue
+			}
+			info, err = os.Stat(path)
+		} else {
+			info, err = entry.Info()
+		}
+		if err != nil {
+			w.onError(err)
+			continue
+		}
+
+		if ignores.ignored(path, info.IsDir()) {
+			continue
+		}
+
+		switch {
+		case info.IsDir():
+			w.walkDir(path, info, ancestors, ignores)
+		case info.Mode().IsRegular():
+			w.onFile(path)
+		}
+	}
+}
+
+// osPath returns the path to hand to the operating system for p, where the
+// empty path stands for the current directory.
+func osPath(p string) string {
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// joinPath returns the path of the entry name in dir. Unlike filepath.Join it
+// keeps dir as given, so that "./" prefixes the user typed are shown back.
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	if strings.HasSuffix(dir, string(os.PathSeparator)) {
+		return dir + name
+	}
+	return dir + string(os.PathSeparator) + name
+}
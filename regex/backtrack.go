@@ -0,0 +1,173 @@
+package regex
+
+// backtracker holds the state of one backtracking search over a line.
+//
+// The result of matching the tokens from tokenIndex at inputIndex depends on
+// nothing else, since patterns have no backreferences, so every pair that failed
+// once is remembered and never explored again. That bounds a search by
+// len(tokens) * len(inputText) steps instead of letting it grow exponentially.
+type backtracker struct {
+	inputText   []byte
+	tokens      []token
+	anchoredEnd bool
+	budget      *budget
+	failed      stateSet // (tokenIndex, inputIndex) pairs known not to match
+	err         error    // set when the budget ran out; aborts the search
+	ends        []int    // stack of the ends of the runs being tried by matchRepeatedRunes
+}
+
+// newBacktracker prepares a backtracking search of inputText.
+func newBacktracker(inputText []byte, tokens []token, anchoredEnd bool, b *budget) *backtracker {
+	return &backtracker{
+		inputText:   inputText,
+		tokens:      tokens,
+		anchoredEnd: anchoredEnd,
+		budget:      b,
+		failed:      newStateSet((len(tokens) + 1) * (len(inputText) + 1)),
+	}
+}
+
+// matchFromPosition attempts to match all tokens sequentially starting from the given position.
+// It handles quantifiers like + (one or more) using backtracking.
+// It returns the index just past the match, or -1 if the tokens don't match from
+// the start position, and an error if the search was aborted by its budget.
+func (bt *backtracker) matchFromPosition(startIndex int) (int, error) {
+	end := bt.matchFromPositionRecursive(0, startIndex)
+	return end, bt.err
+}
+
+// matchFromPositionRecursive recursively matches tokens with backtracking support.
+// It tries different match lengths for quantified tokens and backtracks on failure.
+// Quantifiers are greedy: the longest repetition is tried first, so the first
+// successful path is the leftmost-first match of Perl and the regexp package.
+//
+// Parameters:
+//   - tokenIndex: current token being matched
+//   - inputIndex: current position in the input text
+//
+// Returns the end of the match if all remaining tokens can be matched from the
+// current position, or -1.
+func (bt *backtracker) matchFromPositionRecursive(tokenIndex int, inputIndex int) int {
+	if bt.err != nil {
+		return -1
+	}
+
+	// Base case: all tokens matched successfully
+	if tokenIndex >= len(bt.tokens) {
+		if bt.anchoredEnd && inputIndex != len(bt.inputText) {
+			return -1
+		}
+		return inputIndex
+	}
+
+	memo := tokenIndex*(len(bt.inputText)+1) + inputIndex
+	if bt.failed.has(memo) {
+		return -1
+	}
+	// Only the pairs explored count as steps, so the limit tracks the real work
+	if err := bt.budget.step(); err != nil {
+		bt.err = err
+		return -1
+	}
+	if end := bt.matchToken(tokenIndex, inputIndex); end >= 0 {
+		return end
+	}
+	if bt.err == nil {
+		bt.failed.add(memo)
+	}
+	return -1
+}
+
+// matchToken tries every way of matching the token at tokenIndex from inputIndex
+// and recurses into the rest of the pattern. It returns the end of the match or -1.
+func (bt *backtracker) matchToken(tokenIndex int, inputIndex int) int {
+	inputText := bt.inputText
+	tok := bt.tokens[tokenIndex]
+
+	if tok.Quantifier == oneOrMore && tok.multibyte() {
+		return bt.matchRepeatedRunes(tokenIndex, inputIndex)
+	} else if tok.Quantifier == oneOrMore {
+		// + quantifier: match one or more times with backtracking
+		// Find the longest run of matching characters
+		last := inputIndex
+		for last < len(inputText) && matchToken(tok, inputText[last]) {
+			last++
+		}
+
+		// Try matching n, n-1, ..., 1 times (backtracking)
+		// Must match at least once
+		for i := last; i > inputIndex; i-- {
+			// Try matching the rest of the pattern with current match count
+			if end := bt.matchFromPositionRecursive(tokenIndex+1, i); end >= 0 {
+				return end // Found a successful match
+			}
+			if bt.err != nil {
+				return -1
+			}
+			// If failed, backtrack and try matching one less character
+		}
+
+		return -1 // All attempts failed
+
+	} else if tok.Quantifier == zeroOrOne {
+		// ? quantifier: match zero or one time with backtracking
+		// Try matching 1 time first (consume one character)
+		if width := bt.width(tok, inputIndex); width > 0 {
+			if end := bt.matchFromPositionRecursive(tokenIndex+1, inputIndex+width); end >= 0 {
+				return end
+			}
+		}
+
+		// Try matching 0 times (skip the token)
+		return bt.matchFromPositionRecursive(tokenIndex+1, inputIndex)
+
+	} else {
+		// No quantifier: match exactly once
+		if width := bt.width(tok, inputIndex); width > 0 {
+			// Recursively match the rest of the pattern
+			return bt.matchFromPositionRecursive(tokenIndex+1, inputIndex+width)
+		}
+
+		return -1 // Token doesn't match
+	}
+}
+
+// matchRepeatedRunes is matchToken for a multibyte token with the + quantifier.
+// The run of characters it matches is decoded first, then the rest of the
+// pattern is tried after n, n-1, ..., 1 of them.
+func (bt *backtracker) matchRepeatedRunes(tokenIndex int, inputIndex int) int {
+	tok := bt.tokens[tokenIndex]
+	base := len(bt.ends)
+	defer func() { bt.ends = bt.ends[:base] }()
+	for i := inputIndex; ; {
+		width := bt.width(tok, i)
+		if width < 0 {
+			break
+		}
+		i += width
+		bt.ends = append(bt.ends, i)
+	}
+
+	// The recursive calls push their own runs above this one
+	for k := len(bt.ends) - 1; k >= base; k-- {
+		if end := bt.matchFromPositionRecursive(tokenIndex+1, bt.ends[k]); end >= 0 {
+			return end
+		}
+		if bt.err != nil {
+			return -1
+		}
+	}
+	return -1
+}
+
+// width returns the number of bytes the token matches at inputIndex, or -1 if
+// it doesn't match there.
+func (bt *backtracker) width(tok token, inputIndex int) int {
+	if tok.multibyte() {
+		return tok.set.width(bt.inputText[inputIndex:])
+	}
+	if inputIndex < len(bt.inputText) && matchToken(tok, bt.inputText[inputIndex]) {
+		return 1
+	}
+	return -1
+}
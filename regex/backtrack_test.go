@@ -0,0 +1,155 @@
+package regex
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBacktrackMatch(t *testing.T) {
+	for _, tt := range engineTests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		re := regexp.MustCompile(tt.pattern)
+		for _, input := range tt.inputs {
+			want := re.FindStringIndex(input)
+			got, err := p.backtrack(context.Background(), []byte(input))
+			if err != nil {
+				t.Fatalf("backtrack() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("backtrack(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
+
+func TestBacktrackMemoization(t *testing.T) {
+	// a?^n a^n against a^n takes 2^n steps without memoization
+	n := 30
+	pattern := strings.Repeat("a?", n) + strings.Repeat("a", n)
+	p, err := compilePattern(pattern)
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+
+	input := []byte(strings.Repeat("a", n))
+	if loc, err := p.backtrack(context.Background(), input); loc == nil || err != nil {
+		t.Errorf("backtrack() = %v, %v, want a match", loc, err)
+	}
+	if loc, err := p.backtrack(context.Background(), input[1:]); loc != nil || err != nil {
+		t.Errorf("backtrack() = %v, %v on a shorter input, want no match", loc, err)
+	}
+}
+
+func TestBacktrackMemoizedStepsAreFree(t *testing.T) {
+	// Every offset of the run tries every split between the two a+, but each
+	// (token, offset) pair is only explored once; the rest are memo hits
+	p, err := compilePattern("a+a+c")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	input := []byte(strings.Repeat("a", 2000))
+	p.limits = matchLimits{maxSteps: (len(p.tokens) + 1) * (len(input) + 1)}
+
+	if loc, err := p.backtrack(context.Background(), input); loc != nil || err != nil {
+		t.Errorf("backtrack() = %v, %v, want no match and no error", loc, err)
+	}
+}
+
+func TestBacktrackLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  matchLimits
+		wantErr error
+	}{
+		{name: "no limits", limits: matchLimits{}, wantErr: nil},
+		{name: "generous step limit", limits: matchLimits{maxSteps: 1_000_000}, wantErr: nil},
+		{name: "step limit exceeded", limits: matchLimits{maxSteps: 100}, wantErr: ErrBacktrackLimit},
+		{name: "deadline passed", limits: matchLimits{deadline: time.Now().Add(-time.Second)}, wantErr: ErrTimeout},
+	}
+
+	p, err := compilePattern("\\w+\\d+x")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	input := []byte(strings.Repeat("ab1", 300))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.limits = tt.limits
+			loc, err := p.backtrack(context.Background(), input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("backtrack() error = %v, want %v", err, tt.wantErr)
+			}
+			if loc != nil {
+				t.Errorf("backtrack() = %v, want no match", loc)
+			}
+		})
+	}
+}
+
+func TestLinearEnginesDeadline(t *testing.T) {
+	p, err := compilePattern("\\d+x")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	p.limits = matchLimits{deadline: time.Now().Add(-time.Second)}
+	input := []byte(strings.Repeat("1", 2*checkInterval) + "x")
+
+	if _, err := p.match(context.Background(), input); !errors.Is(err, ErrTimeout) {
+		t.Errorf("match() error = %v, want %v", err, ErrTimeout)
+	}
+	b := newBudget(context.Background(), p.limits)
+	if _, err := p.dfas.get().match(input, b); !errors.Is(err, ErrTimeout) {
+		t.Errorf("dfa.match() error = %v, want %v", err, ErrTimeout)
+	}
+}
+
+func TestPrefilterDeadline(t *testing.T) {
+	// The prefilter alone settles these searches, yet they must time out too
+	for _, tt := range []struct{ pattern, input string }{
+		{"foo", "a foo"},            // found by the literal search
+		{"foo\\d", "no match here"}, // rejected by the literal search
+	} {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern() error = %v", err)
+		}
+		p.limits = matchLimits{deadline: time.Now().Add(-time.Second)}
+		if _, err := p.match(context.Background(), []byte(tt.input)); !errors.Is(err, ErrTimeout) {
+			t.Errorf("match(%q, %q) error = %v, want %v", tt.pattern, tt.input, err, ErrTimeout)
+		}
+	}
+}
+
+func TestBacktrackContext(t *testing.T) {
+	p, err := compilePattern("\\w+\\d+x")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	p.limits = matchLimits{}
+	input := []byte(strings.Repeat("ab1", 300))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// The backtracker alone only notices after checkInterval steps
+	if _, err := p.backtrack(ctx, input); !errors.Is(err, context.Canceled) {
+		t.Errorf("backtrack() error = %v, want %v", err, context.Canceled)
+	}
+
+	b := newBudget(ctx, p.limits)
+	for _, m := range []interface {
+		match([]byte, *budget) (bool, error)
+	}{p.dfas.get(), p.anchored} {
+		if _, err := m.match(input, b); !errors.Is(err, context.Canceled) {
+			t.Errorf("%T.match() error = %v, want %v", m, err, context.Canceled)
+		}
+	}
+}
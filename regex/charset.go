@@ -0,0 +1,228 @@
+package regex
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// byteSet is a 256-bit lookup table with one bit per byte value.
+type byteSet [4]uint64
+
+func (s *byteSet) add(b byte) {
+	s[b/64] |= 1 << (b % 64)
+}
+
+func (s *byteSet) has(b byte) bool {
+	return s[b/64]&(1<<(b%64)) != 0
+}
+
+// negate flips every bit in the set.
+func (s *byteSet) negate() {
+	for i := range s {
+		s[i] = ^s[i]
+	}
+}
+
+// runeRange is an inclusive range of runes.
+type runeRange struct {
+	lo, hi rune
+}
+
+// charSet is the precompiled form of a token: the bytes it matches.
+//
+// The dot and character classes match whole UTF-8 encoded characters. The
+// ASCII characters they match live in the bit table, and every other character
+// is one of the byte sequences in seqs, where the i-th byte of a sequence is any
+// byte in its i-th set.
+type charSet struct {
+	bytes byteSet
+	seqs  [][]byteSet
+}
+
+// compileCharSet builds the lookup table for a token.
+func compileCharSet(tok token) *charSet {
+	set := &charSet{}
+
+	switch tok.Type {
+	case literal:
+		set.bytes.add(tok.Value[0])
+
+	case digit:
+		for i := 0; i < len(digits); i++ {
+			set.bytes.add(digits[i])
+		}
+
+	case word:
+		for i := 0; i < len(wordChars); i++ {
+			set.bytes.add(wordChars[i])
+		}
+
+	case charClass, negCharClass:
+		if tok.Value == "" {
+			// Empty classes match nothing, negated or not
+			return set
+		}
+		if utf8.ValidString(tok.Value) {
+			set.addRunes(tok.Value, tok.Type == negCharClass)
+			return set
+		}
+		// A body that isn't valid UTF-8 lists bytes rather than characters
+		for i := 0; i < len(tok.Value); i++ {
+			set.bytes.add(tok.Value[i])
+		}
+		if tok.Type == negCharClass {
+			set.bytes.negate()
+		}
+
+	case dot:
+		// Dot matches any character except newline
+		set.addRunes("\n", true)
+	}
+
+	return set
+}
+
+// addRunes adds the characters matched by a class body that is valid UTF-8.
+// A negated class matches every ASCII byte and every valid UTF-8 encoded
+// character that isn't a member, but never a byte that is part of a multibyte
+// character on its own, or one that isn't valid UTF-8.
+func (s *charSet) addRunes(class string, negated bool) {
+	var ranges []runeRange
+	for _, r := range class {
+		if r < utf8.RuneSelf {
+			s.bytes.add(byte(r))
+			continue
+		}
+		ranges = append(ranges, runeRange{r, r})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].lo < ranges[j].lo })
+	var merged []runeRange
+	for _, rr := range ranges {
+		if n := len(merged); n > 0 && rr.lo <= merged[n-1].hi+1 {
+			merged[n-1].hi = max(merged[n-1].hi, rr.hi)
+			continue
+		}
+		merged = append(merged, rr)
+	}
+
+	if !negated {
+		for _, rr := range merged {
+			s.addRuneRange(rr.lo, rr.hi)
+		}
+		return
+	}
+
+	// Only the ASCII half of the table is in play, the rest is left to seqs
+	s.bytes.negate()
+	s.bytes[2], s.bytes[3] = 0, 0
+	lo := rune(utf8.RuneSelf)
+	for _, rr := range merged {
+		if lo < rr.lo {
+			s.addRuneRange(lo, rr.lo-1)
+		}
+		lo = rr.hi + 1
+	}
+	if lo <= unicode.MaxRune {
+		s.addRuneRange(lo, unicode.MaxRune)
+	}
+}
+
+// addRuneRange adds the UTF-8 encodings of the runes from lo to hi, both
+// non-ASCII, as byte sequences.
+//
+// The range is split until the encodings of its runes differ only in bytes that
+// can vary independently of one another, so that each part is a single
+// sequence of byte ranges. The split follows the utf8-ranges algorithm of Rust's
+// regex crate.
+func (s *charSet) addRuneRange(lo, hi rune) {
+	// Surrogates have no UTF-8 encoding
+	if lo <= 0xdfff && hi >= 0xd800 {
+		if lo < 0xd800 {
+			s.addRuneRange(lo, 0xd7ff)
+		}
+		if hi > 0xdfff {
+			s.addRuneRange(0xe000, hi)
+		}
+		return
+	}
+
+	// Runes of each encoded length are handled apart
+	for _, last := range []rune{0x7ff, 0xffff} {
+		if lo <= last && hi > last {
+			s.addRuneRange(lo, last)
+			s.addRuneRange(last+1, hi)
+			return
+		}
+	}
+
+	// Bytes after the first carry 6 bits each. Below the first byte that
+	// differs, the lower bits of lo must all be 0 and those of hi all 1.
+	for i := 1; i < utf8.UTFMax; i++ {
+		mask := rune(1)<<(6*i) - 1
+		if lo&^mask == hi&^mask {
+			continue
+		}
+		if lo&mask != 0 {
+			s.addRuneRange(lo, lo|mask)
+			s.addRuneRange(lo|mask+1, hi)
+			return
+		}
+		if hi&mask != mask {
+			s.addRuneRange(lo, hi&^mask-1)
+			s.addRuneRange(hi&^mask, hi)
+			return
+		}
+	}
+
+	var from, to [utf8.UTFMax]byte
+	n := utf8.EncodeRune(from[:], lo)
+	utf8.EncodeRune(to[:], hi)
+	seq := make([]byteSet, n)
+	for i := range seq {
+		for b := int(from[i]); b <= int(to[i]); b++ {
+			seq[i].add(byte(b))
+		}
+	}
+	s.seqs = append(s.seqs, seq)
+}
+
+// width returns the length of the character the set matches at the start of
+// input, or -1 if it matches none. Since UTF-8 is a prefix code, at most one
+// of the sequences can match.
+func (s *charSet) width(input []byte) int {
+	if len(input) == 0 {
+		return -1
+	}
+	if s.bytes.has(input[0]) {
+		return 1
+	}
+next:
+	for _, seq := range s.seqs {
+		if len(seq) > len(input) {
+			continue
+		}
+		for i := range seq {
+			if !seq[i].has(input[i]) {
+				continue next
+			}
+		}
+		return len(seq)
+	}
+	return -1
+}
+
+// reversed returns the set with every byte sequence reversed, for matching
+// backwards.
+func (s *charSet) reversed() *charSet {
+	r := &charSet{bytes: s.bytes, seqs: make([][]byteSet, len(s.seqs))}
+	for i, seq := range s.seqs {
+		rev := make([]byteSet, len(seq))
+		for j := range seq {
+			rev[len(seq)-1-j] = seq[j]
+		}
+		r.seqs[i] = rev
+	}
+	return r
+}
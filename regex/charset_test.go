@@ -0,0 +1,76 @@
+package regex
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestCompileCharSet(t *testing.T) {
+	tokens := []token{
+		{Type: literal, Value: "a"},
+		{Type: literal, Value: "\\"},
+		{Type: digit, Value: "\\d"},
+		{Type: word, Value: "\\w"},
+		{Type: charClass, Value: "abc"},
+		{Type: charClass, Value: ""},
+		{Type: negCharClass, Value: "abc"},
+		{Type: negCharClass, Value: ""},
+		{Type: dot, Value: "."},
+	}
+
+	for _, tok := range tokens {
+		compiled := tok
+		compiled.set = compileCharSet(tok)
+		for b := 0; b < 256; b++ {
+			if compiled.multibyte() && b >= utf8.RuneSelf {
+				// Matched through the byte sequences, see TestCompileCharSetRunes
+				continue
+			}
+			// The hand-built token goes through the string comparison fallback
+			want := matchToken(tok, byte(b))
+			if got := matchToken(compiled, byte(b)); got != want {
+				t.Errorf("token %+v, byte %#x: compiled = %v, want %v", tok, b, got, want)
+			}
+		}
+	}
+}
+
+func TestCompileCharSetRunes(t *testing.T) {
+	classes := []struct {
+		class   string
+		negated bool
+	}{
+		{class: "é"},
+		{class: "aé日😀"},
+		{class: "é", negated: true},
+		{class: "aßπ€\U0010ffff", negated: true},
+	}
+
+	for _, tt := range classes {
+		set := &charSet{}
+		set.addRunes(tt.class, tt.negated)
+		var buf [utf8.UTFMax]byte
+		for r := rune(0); r <= unicode.MaxRune; r++ {
+			if !utf8.ValidRune(r) {
+				continue
+			}
+			n := utf8.EncodeRune(buf[:], r)
+			want := -1
+			if strings.ContainsRune(tt.class, r) != tt.negated {
+				want = n
+			}
+			if got := set.width(buf[:n]); got != want {
+				t.Fatalf("class %q (negated %v), rune %U: width = %d, want %d", tt.class, tt.negated, r, got, want)
+			}
+		}
+
+		// Bytes that aren't valid UTF-8 match no class
+		for _, input := range []string{"\xe9", "\xa9", "\xc0\x80", "\xed\xa0\x80", "\xf4\x90\x80\x80"} {
+			if got := set.width([]byte(input)); got != -1 {
+				t.Errorf("class %q (negated %v), input %q: width = %d, want -1", tt.class, tt.negated, input, got)
+			}
+		}
+	}
+}
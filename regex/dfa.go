@@ -0,0 +1,293 @@
+package regex
+
+import "sync"
+
+// defaultDFACacheSize is the default memory budget, in bytes, for cached DFA states.
+const defaultDFACacheSize = 1 << 20
+
+// automaton is a position automaton that a lazy DFA can be built over: nfa for a
+// single pattern, or setNFA for the patterns of a RegexSet.
+type automaton interface {
+	numStates() int
+	start(set stateSet)
+	step(cur stateSet, b byte, next stateSet)
+	accepting(set stateSet) []int // the patterns that have matched in set
+}
+
+// lazyDFA is a lazily built deterministic automaton over an automaton.
+//
+// Each DFA state stands for a set of NFA states and is only created the first
+// time the input reaches it. Bytes are grouped into equivalence classes that no
+// state's byte set can tell apart, so the transition table of a state has one
+// entry per class instead of one per byte.
+//
+// The cache of states is bounded by cacheSize. When it is full the DFA stops
+// building states and the search continues with plain NFA simulation.
+type lazyDFA struct {
+	m         automaton
+	classes   [256]byte // byte -> equivalence class
+	nclasses  int
+	states    []*dfaState
+	index     map[string]int // stateSet key -> index into states
+	cacheSize int            // memory budget for cached states, in bytes
+	used      int            // memory currently used by cached states, in bytes
+}
+
+// dfaState is a cached DFA state.
+type dfaState struct {
+	set       stateSet
+	accept    bool  // some pattern has matched in this state
+	accepting []int // the patterns that have matched in this state
+	next      []int // next state for each byte class; unknown is -1
+}
+
+// Sentinel transitions stored in dfaState.next.
+const (
+	dfaUnknown = -1 // transition not computed yet
+	dfaFull    = -2 // transition could not be cached because the cache is full
+)
+
+// newLazyDFA creates a lazy DFA for m that caches at most cacheSize bytes of
+// states. The byte classes are those that the byte sets of m's states can tell
+// apart.
+func newLazyDFA(m automaton, sets []*byteSet, cacheSize int) lazyDFA {
+	d := lazyDFA{
+		m:         m,
+		index:     make(map[string]int),
+		cacheSize: cacheSize,
+	}
+	d.nclasses = byteClasses(sets, &d.classes)
+	return d
+}
+
+// dfa is the lazy DFA of a single pattern.
+type dfa struct {
+	lazyDFA
+	nfa *nfa
+}
+
+// newDFA creates a lazy DFA for m that caches at most cacheSize bytes of states.
+func newDFA(m *nfa, cacheSize int) *dfa {
+	return &dfa{lazyDFA: newLazyDFA(m, m.sets[1:], cacheSize), nfa: m}
+}
+
+// dfaPool hands out lazy DFAs for the same automaton. A DFA fills in its cache as
+// it runs, so every concurrent search needs one of its own; the pool lets the
+// searches that follow reuse the states built by earlier ones.
+type dfaPool struct {
+	pool sync.Pool
+}
+
+// newDFAPool creates a pool of DFAs for m.
+func newDFAPool(m *nfa) *dfaPool {
+	proto := newDFA(m, defaultDFACacheSize)
+	dp := &dfaPool{}
+	dp.pool.New = func() any {
+		// The byte classes only depend on the tokens, so they are computed once
+		d := *proto
+		d.index = make(map[string]int)
+		return &d
+	}
+	return dp
+}
+
+// get takes a DFA out of the pool.
+func (dp *dfaPool) get() *dfa {
+	return dp.pool.Get().(*dfa)
+}
+
+// put returns a DFA to the pool once the search using it is done.
+func (dp *dfaPool) put(d *dfa) {
+	dp.pool.Put(d)
+}
+
+// byteClasses partitions the 256 byte values into classes such that every set
+// holds either all or none of the bytes in a class. It returns the class count.
+func byteClasses(sets []*byteSet, classes *[256]byte) int {
+	n := 1 // every byte starts in class 0
+	for _, set := range sets {
+		// Split each existing class into the bytes in the set and the rest
+		split := make(map[[2]int]int)
+		next := 0
+		for b := 0; b < 256; b++ {
+			member := 0
+			if set.has(byte(b)) {
+				member = 1
+			}
+			k := [2]int{int(classes[b]), member}
+			c, ok := split[k]
+			if !ok {
+				c = next
+				split[k] = c
+				next++
+			}
+			classes[b] = byte(c)
+		}
+		n = next
+	}
+	return n
+}
+
+// stateCost estimates the memory, in bytes, taken by a cached state.
+func (d *lazyDFA) stateCost(set stateSet, accepting []int) int {
+	return 64 + len(set)*8*2 + len(accepting)*8 + d.nclasses*8
+}
+
+// lookup returns the index of the cached state for set, adding it if needed.
+// It returns false when the state is not cached and the cache is full.
+func (d *lazyDFA) lookup(set stateSet) (int, bool) {
+	key := set.key()
+	if i, ok := d.index[key]; ok {
+		return i, true
+	}
+
+	accepting := d.m.accepting(set)
+	cost := d.stateCost(set, accepting)
+	if d.used+cost > d.cacheSize {
+		return 0, false
+	}
+	d.used += cost
+
+	state := &dfaState{
+		set:       append(stateSet(nil), set...),
+		accept:    len(accepting) > 0,
+		accepting: accepting,
+		next:      make([]int, d.nclasses),
+	}
+	for i := range state.next {
+		state.next[i] = dfaUnknown
+	}
+	d.states = append(d.states, state)
+	d.index[key] = len(d.states) - 1
+	return len(d.states) - 1, true
+}
+
+// transition returns the state reached from state s on byte b, computing and
+// caching it if necessary. It returns dfaFull if the cache has no room left.
+func (d *lazyDFA) transition(s int, b byte) int {
+	state := d.states[s]
+	class := d.classes[b]
+	if next := state.next[class]; next != dfaUnknown {
+		return next
+	}
+
+	set := newStateSet(d.m.numStates())
+	d.m.step(state.set, b, set)
+	next, ok := d.lookup(set)
+	if !ok {
+		next = dfaFull
+	}
+	state.next[class] = next
+	return next
+}
+
+// dfaSearch is what a run of a lazy DFA looks for.
+type dfaSearch interface {
+	// visit is given the state reached after the bytes before offset i and
+	// reports whether the search is over.
+	visit(state *dfaState, i int) bool
+	// finish completes the search from offset i, where the automaton is in the
+	// states cur, by simulating it once the cache is full.
+	finish(i int, cur stateSet) error
+}
+
+// run feeds inputText to the DFA and passes every state it reaches to s, until
+// s is done, the input ends or no match can start or continue.
+func (d *lazyDFA) run(inputText []byte, s dfaSearch, b *budget) error {
+	init := newStateSet(d.m.numStates())
+	d.m.start(init)
+	cur, ok := d.lookup(init)
+	if !ok {
+		return s.finish(0, init)
+	}
+
+	for i := 0; ; i++ {
+		state := d.states[cur]
+		if s.visit(state, i) || i >= len(inputText) {
+			return nil
+		}
+		if state.set.empty() {
+			return nil // dead state: no match can start or continue
+		}
+		if err := b.poll(i); err != nil {
+			return err
+		}
+
+		next := d.transition(cur, inputText[i])
+		if next == dfaFull {
+			// Out of cache: finish the search by simulating the NFA from here
+			return s.finish(i, append(stateSet(nil), state.set...))
+		}
+		cur = next
+	}
+}
+
+// dfaEnd is the search of dfa.earliestEnd.
+type dfaEnd struct {
+	nfa       *nfa
+	inputText []byte
+	b         *budget
+	end       int
+}
+
+func (m *dfaEnd) visit(state *dfaState, i int) bool {
+	if state.accept && (!m.nfa.anchoredEnd || i == len(m.inputText)) {
+		m.end = i
+		return true
+	}
+	return false
+}
+
+func (m *dfaEnd) finish(i int, cur stateSet) error {
+	var err error
+	m.end, err = m.nfa.endFrom(m.inputText, i, cur, m.b)
+	return err
+}
+
+// match reports whether the pattern matches anywhere in inputText.
+func (d *dfa) match(inputText []byte, b *budget) (bool, error) {
+	end, err := d.earliestEnd(inputText, b)
+	return end >= 0, err
+}
+
+// earliestEnd returns the offset in inputText where the first match to end
+// ends, or -1 if the pattern doesn't match.
+func (d *dfa) earliestEnd(inputText []byte, b *budget) (int, error) {
+	m := &dfaEnd{nfa: d.nfa, inputText: inputText, b: b, end: -1}
+	err := d.run(inputText, m, b)
+	return m.end, err
+}
+
+// longestReverse reads inputText backwards from end down to limit at most and
+// returns the lowest offset at which the automaton accepts, or -1 if there is
+// none. Run on the reversed automaton anchored at the end of a match, it finds
+// the leftmost start of the matches that end there.
+func (d *dfa) longestReverse(inputText []byte, end, limit int, b *budget) (int, error) {
+	init := newStateSet(d.nfa.numStates())
+	d.nfa.start(init)
+	s, ok := d.lookup(init)
+	if !ok {
+		return d.nfa.longestReverseFrom(inputText, end, limit, init, -1, b)
+	}
+
+	start := -1
+	for i := end; ; i-- {
+		state := d.states[s]
+		if state.accept && (!d.nfa.anchoredEnd || i == 0) {
+			start = i
+		}
+		if i <= limit || state.set.empty() {
+			return start, nil
+		}
+		if err := b.poll(end - i); err != nil {
+			return -1, err
+		}
+
+		next := d.transition(s, inputText[i-1])
+		if next == dfaFull {
+			// Out of cache: finish the search by simulating the NFA from here
+			return d.nfa.longestReverseFrom(inputText, i, limit, append(stateSet(nil), state.set...), start, b)
+		}
+		s = next
+	}
+}
@@ -0,0 +1,78 @@
+package regex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDFAMatch(t *testing.T) {
+	for _, tt := range engineTests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		for _, input := range tt.inputs {
+			want := referenceMatch(t, tt.pattern, input)
+			d := p.dfas.get()
+			if got := mustMatch(t)(d.match([]byte(input), unlimited())); got != want {
+				t.Errorf("dfa.match(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+			if got := mustMatch(t)(d.nfa.match([]byte(input), unlimited())); got != want {
+				t.Errorf("nfa.match(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+			p.dfas.put(d)
+		}
+	}
+}
+
+func TestDFACacheLimit(t *testing.T) {
+	// A pattern whose DFA needs many states, matched with room for only a few of them
+	pattern := "a.........b"
+	p, err := compilePattern(pattern)
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+
+	m := p.dfas.get().nfa
+	d := newDFA(m, 3*oneStateCost(newDFA(m, defaultDFACacheSize)))
+	input := strings.Repeat("ab", 50) + "a012345678b"
+	if !mustMatch(t)(d.match([]byte(input), unlimited())) {
+		t.Errorf("match() = false with a full cache, want true")
+	}
+	if d.used > d.cacheSize {
+		t.Errorf("cache uses %d bytes, limit is %d", d.used, d.cacheSize)
+	}
+	if len(d.states) > 3 {
+		t.Errorf("cached %d states, want at most 3", len(d.states))
+	}
+
+	if mustMatch(t)(d.match([]byte(strings.Repeat("ab", 50)), unlimited())) {
+		t.Errorf("match() = true with a full cache, want false")
+	}
+}
+
+// oneStateCost returns the cost of one cached state of d.
+func oneStateCost(d *dfa) int {
+	return d.stateCost(newStateSet(d.nfa.numStates()), nil)
+}
+
+func TestByteClasses(t *testing.T) {
+	tokens, err := parseTokens("a\\d[xyz]")
+	if err != nil {
+		t.Fatalf("parseTokens() error = %v", err)
+	}
+
+	var classes [256]byte
+	n := byteClasses(newNFA(tokens, false, false).sets[1:], &classes)
+
+	// {a}, {0-9}, {x,y,z} and everything else
+	if n != 4 {
+		t.Errorf("byteClasses() = %d classes, want 4", n)
+	}
+	if classes['0'] != classes['9'] || classes['x'] != classes['z'] {
+		t.Errorf("bytes matched by the same tokens are in different classes")
+	}
+	if classes['a'] == classes['b'] || classes['b'] != classes['!'] {
+		t.Errorf("classes do not separate 'a' from the other bytes")
+	}
+}
@@ -0,0 +1,83 @@
+package regex
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultBacktrackLimit is the default number of steps a single backtracking
+// search may take before it is aborted.
+const DefaultBacktrackLimit = 10_000_000
+
+// checkInterval is how many steps or bytes an engine processes between checks of
+// the wall-clock deadline and the context, which are much more expensive than a
+// step.
+const checkInterval = 1 << 12
+
+var (
+	// ErrBacktrackLimit is returned when a search takes more backtracking steps than allowed.
+	ErrBacktrackLimit = errors.New("regex: backtracking step limit exceeded")
+	// ErrTimeout is returned when a search runs past its deadline.
+	ErrTimeout = errors.New("regex: match timed out")
+)
+
+// matchLimits bounds the work a search may do.
+type matchLimits struct {
+	maxSteps int       // backtracking steps per search; 0 means unlimited
+	deadline time.Time // wall-clock deadline; zero means none
+}
+
+// budget tracks the work done by one search against its limits and the context
+// the search runs in.
+type budget struct {
+	ctx    context.Context
+	limits matchLimits
+	steps  int
+}
+
+// newBudget starts tracking a search under the given context and limits.
+func newBudget(ctx context.Context, limits matchLimits) *budget {
+	return &budget{ctx: ctx, limits: limits}
+}
+
+// reset starts a new search under the same limits, forgetting the steps taken
+// by the previous one.
+func (b *budget) reset() {
+	b.steps = 0
+}
+
+// step records one backtracking step and reports whether the search must stop.
+// The clock is looked at on the first step, so that even a short search notices
+// a deadline that has already passed, and every checkInterval steps after it.
+func (b *budget) step() error {
+	b.steps++
+	if b.limits.maxSteps > 0 && b.steps > b.limits.maxSteps {
+		return ErrBacktrackLimit
+	}
+	if b.steps%checkInterval == 1 {
+		return b.expired()
+	}
+	return nil
+}
+
+// poll is called by the linear engines for every input byte at offset i. It only
+// looks at the clock every checkInterval bytes.
+func (b *budget) poll(i int) error {
+	if i%checkInterval != 0 {
+		return nil
+	}
+	return b.expired()
+}
+
+// expired reports the context's error once it is done, and ErrTimeout once the
+// deadline has passed.
+func (b *budget) expired() error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	if !b.limits.deadline.IsZero() && time.Now().After(b.limits.deadline) {
+		return ErrTimeout
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package regex
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// defaultBufferSize is the size of the buffer a LineReader reads into.
+const defaultBufferSize = 64 << 10
+
+// maxConsecutiveEmptyReads is how many reads returning no data and no error a
+// LineReader puts up with before giving up with io.ErrNoProgress.
+const maxConsecutiveEmptyReads = 100
+
+// Line is a line of input read by a LineReader.
+type Line struct {
+	Text       []byte // the line without its terminator
+	Terminator []byte // "\n", "\r\n" in CRLF mode, or empty for a last line without one
+	Number     int    // 1-based line number
+	Offset     int64  // byte offset of the start of the line in the input
+}
+
+// LineReader splits an input stream into lines without reading all of it into
+// memory.
+//
+// Input is read into a fixed-size buffer. A line that spans the end of the
+// buffer is moved to the front before the next read, and a line longer than the
+// whole buffer makes it grow until the line fits, so memory use is bounded by
+// the longest line rather than by the size of the input. The buffer goes back to
+// its normal size once the long line has been consumed.
+//
+// Lines end at a newline. In CRLF mode a carriage return right before the
+// newline belongs to the terminator rather than to the line.
+type LineReader struct {
+	r     io.Reader
+	crlf  bool
+	size  int    // normal buffer size
+	buf   []byte // buf[start:end] holds the bytes read but not consumed yet
+	start int
+	end   int
+	eof   bool
+	err   error
+
+	line   Line
+	number int
+	offset int64 // input offset of buf[start]
+}
+
+// NewLineReader returns a LineReader that reads from r.
+func NewLineReader(r io.Reader) *LineReader {
+	return newLineReaderSize(r, defaultBufferSize)
+}
+
+// newLineReaderSize returns a LineReader whose buffer holds size bytes.
+func newLineReaderSize(r io.Reader, size int) *LineReader {
+	return &LineReader{r: r, size: size, buf: make([]byte, size)}
+}
+
+// SetCRLF turns CRLF mode on or off. It is off by default.
+func (lr *LineReader) SetCRLF(on bool) {
+	lr.crlf = on
+}
+
+// Next advances to the next line, which is then available through Line. It
+// returns false at the end of the input or after a read error; Err tells them
+// apart. A final line without a newline is still returned.
+func (lr *LineReader) Next() bool {
+	scanned := 0 // bytes of buf[start:end] already known to hold no newline
+	for {
+		if i := bytes.IndexByte(lr.buf[lr.start+scanned:lr.end], '\n'); i >= 0 {
+			lr.emit(lr.start+scanned+i, lr.start+scanned+i+1)
+			return true
+		}
+		scanned = lr.end - lr.start
+
+		if lr.eof || lr.err != nil {
+			if lr.start < lr.end {
+				lr.emit(lr.end, lr.end)
+				return true
+			}
+			return false
+		}
+		lr.fill()
+	}
+}
+
+// emit makes buf[start:lineEnd] the current line and consumes the input up to
+// next.
+func (lr *LineReader) emit(lineEnd, next int) {
+	if lr.crlf && lineEnd < next && lineEnd > lr.start && lr.buf[lineEnd-1] == '\r' {
+		lineEnd--
+	}
+	lr.number++
+	lr.line = Line{
+		Text:       lr.buf[lr.start:lineEnd:lineEnd],
+		Terminator: lr.buf[lineEnd:next:next],
+		Number:     lr.number,
+		Offset:     lr.offset,
+	}
+	lr.offset += int64(next - lr.start)
+	lr.start = next
+}
+
+// fill reads more input after the unconsumed bytes, first making room by moving
+// them to the front of the buffer or, if they already fill it, growing it.
+func (lr *LineReader) fill() {
+	pending := lr.end - lr.start
+	switch {
+	case pending == len(lr.buf):
+		grown := make([]byte, 2*len(lr.buf))
+		copy(grown, lr.buf[lr.start:lr.end])
+		lr.buf = grown
+	case len(lr.buf) > lr.size && pending < lr.size:
+		// A long line has been consumed; drop the oversized buffer
+		shrunk := make([]byte, lr.size)
+		copy(shrunk, lr.buf[lr.start:lr.end])
+		lr.buf = shrunk
+	default:
+		copy(lr.buf, lr.buf[lr.start:lr.end])
+	}
+	lr.start, lr.end = 0, pending
+
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		n, err := lr.r.Read(lr.buf[lr.end:])
+		lr.end += n
+		if err == io.EOF {
+			lr.eof = true
+			return
+		}
+		if err != nil {
+			lr.err = err
+			return
+		}
+		if n > 0 {
+			return
+		}
+	}
+	lr.err = io.ErrNoProgress
+}
+
+// Line returns the current line. Its Text is only valid until the next call to
+// Next.
+func (lr *LineReader) Line() Line {
+	return lr.line
+}
+
+// Err returns the first read error other than io.EOF.
+func (lr *LineReader) Err() error {
+	return lr.err
+}
+
+// Scan reads r line by line and calls fn with every line that contains a match,
+// until fn returns false. The input is never held in memory as a whole, so r can
+// be arbitrarily large. Scan returns the first read or search error.
+func (re *Regexp) Scan(r io.Reader, fn func(line Line) bool) error {
+	return re.ScanContext(context.Background(), r, fn)
+}
+
+// ScanContext is like Scan but gives up with ctx.Err() once ctx is done. It is
+// checked before every line and while each line is searched, but a read that
+// blocks is not interrupted.
+func (re *Regexp) ScanContext(ctx context.Context, r io.Reader, fn func(line Line) bool) error {
+	lr := NewLineReader(r)
+	for lr.Next() {
+		line := lr.Line()
+		ok, err := re.prog.match(ctx, line.Text)
+		if err != nil {
+			return err
+		}
+		if ok && !fn(line) {
+			return nil
+		}
+	}
+	return lr.Err()
+}
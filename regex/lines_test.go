@@ -0,0 +1,218 @@
+package regex
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readLines collects every line of input read through a LineReader with the
+// given buffer size.
+func readLines(r io.Reader, size int) ([]Line, error) {
+	return collectLines(newLineReaderSize(r, size))
+}
+
+// collectLines returns copies of all the lines left in lr.
+func collectLines(lr *LineReader) ([]Line, error) {
+	var lines []Line
+	for lr.Next() {
+		line := lr.Line()
+		line.Text = bytes.Clone(line.Text)
+		line.Terminator = bytes.Clone(line.Terminator)
+		lines = append(lines, line)
+	}
+	return lines, lr.Err()
+}
+
+// nl is the terminator of lines ending with a plain newline.
+var nl = []byte("\n")
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("x", 100)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []Line
+	}{
+		{"empty input", "", nil},
+		{"single newline", "\n", []Line{{Text: []byte{}, Terminator: nl, Number: 1, Offset: 0}}},
+		{"no trailing newline", "ab", []Line{{Text: []byte("ab"), Terminator: []byte{}, Number: 1, Offset: 0}}},
+		{
+			name:  "lines span buffer boundaries",
+			input: "abc\ndefgh\ni\n\njk",
+			want: []Line{
+				{Text: []byte("abc"), Terminator: nl, Number: 1, Offset: 0},
+				{Text: []byte("defgh"), Terminator: nl, Number: 2, Offset: 4},
+				{Text: []byte("i"), Terminator: nl, Number: 3, Offset: 10},
+				{Text: []byte{}, Terminator: nl, Number: 4, Offset: 12},
+				{Text: []byte("jk"), Terminator: []byte{}, Number: 5, Offset: 13},
+			},
+		},
+		{
+			name:  "line longer than the buffer",
+			input: "a\n" + long + "\nb\n",
+			want: []Line{
+				{Text: []byte("a"), Terminator: nl, Number: 1, Offset: 0},
+				{Text: []byte(long), Terminator: nl, Number: 2, Offset: 2},
+				{Text: []byte("b"), Terminator: nl, Number: 3, Offset: 103},
+			},
+		},
+	}
+
+	readers := []struct {
+		name string
+		wrap func(io.Reader) io.Reader
+	}{
+		{"plain", func(r io.Reader) io.Reader { return r }},
+		{"one byte", iotest.OneByteReader},
+		{"half", iotest.HalfReader},
+		{"data with EOF", iotest.DataErrReader},
+	}
+
+	for _, tt := range tests {
+		for _, rd := range readers {
+			t.Run(tt.name+"/"+rd.name, func(t *testing.T) {
+				got, err := readLines(rd.wrap(strings.NewReader(tt.input)), 4)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("lines = %q; want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestLineReaderCRLF(t *testing.T) {
+	input := "a\r\n\r\nb\rc\n\r\rd\r"
+	crlf := []byte("\r\n")
+
+	tests := []struct {
+		name string
+		crlf bool
+		want []Line
+	}{
+		{
+			name: "off",
+			want: []Line{
+				{Text: []byte("a\r"), Terminator: nl, Number: 1, Offset: 0},
+				{Text: []byte("\r"), Terminator: nl, Number: 2, Offset: 3},
+				{Text: []byte("b\rc"), Terminator: nl, Number: 3, Offset: 5},
+				{Text: []byte("\r\rd\r"), Terminator: []byte{}, Number: 4, Offset: 9},
+			},
+		},
+		{
+			name: "on",
+			crlf: true,
+			want: []Line{
+				{Text: []byte("a"), Terminator: crlf, Number: 1, Offset: 0},
+				{Text: []byte{}, Terminator: crlf, Number: 2, Offset: 3},
+				{Text: []byte("b\rc"), Terminator: nl, Number: 3, Offset: 5},
+				{Text: []byte("\r\rd\r"), Terminator: []byte{}, Number: 4, Offset: 9},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A small buffer splits some of the CRLF pairs across reads
+			lr := newLineReaderSize(iotest.OneByteReader(strings.NewReader(input)), 2)
+			lr.SetCRLF(tt.crlf)
+			got, err := collectLines(lr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineReaderShrinksAfterLongLine(t *testing.T) {
+	input := strings.Repeat("x", 100) + "\nshort\nlines\n"
+	lr := newLineReaderSize(strings.NewReader(input), 8)
+	for lr.Next() {
+	}
+	if err := lr.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lr.buf) != 8 {
+		t.Errorf("buffer holds %d bytes after the long line; want 8", len(lr.buf))
+	}
+}
+
+func TestLineReaderError(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("ok\npartial"), iotest.ErrReader(errRead))
+
+	got, err := readLines(r, 4)
+	if !errors.Is(err, errRead) {
+		t.Errorf("error = %v; want %v", err, errRead)
+	}
+	want := []Line{
+		{Text: []byte("ok"), Terminator: nl, Number: 1, Offset: 0},
+		{Text: []byte("partial"), Terminator: []byte{}, Number: 2, Offset: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %q; want %q", got, want)
+	}
+}
+
+// emptyReader returns no data and no error, forever.
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) { return 0, nil }
+
+func TestLineReaderNoProgress(t *testing.T) {
+	if _, err := readLines(emptyReader{}, 4); !errors.Is(err, io.ErrNoProgress) {
+		t.Errorf("error = %v; want %v", err, io.ErrNoProgress)
+	}
+}
+
+func TestScan(t *testing.T) {
+	input := "apple\nbanana\n" + strings.Repeat("b", 200_000) + "apple\ncherry\napple pie"
+	re := MustCompile("apple")
+
+	var got []int
+	err := re.Scan(strings.NewReader(input), func(line Line) bool {
+		got = append(got, line.Number)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int{1, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matching lines = %v; want %v", got, want)
+	}
+
+	calls := 0
+	err = re.Scan(strings.NewReader(input), func(Line) bool {
+		calls++
+		return false
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("Scan stopping after the first line: calls = %d, err = %v; want 1, nil", calls, err)
+	}
+}
+
+func TestScanAnchorsPerLine(t *testing.T) {
+	re := MustCompile("^b+$")
+	var got []string
+	err := re.Scan(strings.NewReader("abb\nbbb\nbba\nb"), func(line Line) bool {
+		got = append(got, string(line.Text))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"bbb", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matching lines = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,260 @@
+package regex
+
+// nfa is a position automaton (Glushkov construction) built from the pattern tokens.
+//
+// State 0 is the start state and every other state is a position in the
+// pattern, entered by consuming one byte of its set. A token that matches single
+// bytes has one position, so state i+1 means "token i was just consumed" unless
+// an earlier token is multibyte; a multibyte token has a chain of positions for
+// each of its byte sequences. The automaton has no epsilon transitions, so a set
+// of states can be advanced one byte at a time.
+type nfa struct {
+	sets          []*byteSet // sets[s] holds the bytes consumed when entering s; nil for the start state
+	follow        [][]int    // follow[s] lists the states reachable from s by consuming one byte
+	accept        []bool     // accept[s] reports whether the whole pattern has matched in state s
+	anchoredStart bool       // pattern started with ^: a match may only begin at offset 0
+	anchoredEnd   bool       // pattern ended with $: a match must end at the end of the input
+}
+
+// newNFA builds the position automaton for the given tokens.
+func newNFA(tokens []token, anchoredStart, anchoredEnd bool) *nfa {
+	n := len(tokens)
+	m := &nfa{
+		sets:          []*byteSet{nil},
+		follow:        [][]int{nil},
+		accept:        []bool{false},
+		anchoredStart: anchoredStart,
+		anchoredEnd:   anchoredEnd,
+	}
+
+	// heads[i] lists the positions token i is entered by, tails[i] those it
+	// is left from
+	heads := make([][]int, n)
+	tails := make([][]int, n)
+	for i, tok := range tokens {
+		set := tok.set
+		if set == nil {
+			set = compileCharSet(tok)
+		}
+		if set.seqs == nil || set.bytes != (byteSet{}) {
+			s := m.addState(&set.bytes)
+			heads[i] = append(heads[i], s)
+			tails[i] = append(tails[i], s)
+		}
+		for _, seq := range set.seqs {
+			s := m.addState(&seq[0])
+			heads[i] = append(heads[i], s)
+			for j := 1; j < len(seq); j++ {
+				next := m.addState(&seq[j])
+				m.follow[s] = append(m.follow[s], next)
+				s = next
+			}
+			tails[i] = append(tails[i], s)
+		}
+	}
+
+	// first[k] lists the states that can be entered next when tokens k.. remain,
+	// nullable[k] reports whether tokens k.. can all be skipped.
+	first := make([][]int, n+1)
+	nullable := make([]bool, n+1)
+	nullable[n] = true
+	for k := n - 1; k >= 0; k-- {
+		first[k] = heads[k]
+		if tokens[k].Quantifier == zeroOrOne {
+			first[k] = append(first[k][:len(first[k]):len(first[k])], first[k+1]...)
+			nullable[k] = nullable[k+1]
+		}
+	}
+
+	m.follow[0] = first[0]
+	m.accept[0] = nullable[0]
+	for i, tok := range tokens {
+		for _, s := range tails[i] {
+			if tok.Quantifier == oneOrMore {
+				// + loops back into its own positions
+				m.follow[s] = append(m.follow[s], heads[i]...)
+			}
+			m.follow[s] = append(m.follow[s], first[i+1]...)
+			m.accept[s] = nullable[i+1]
+		}
+	}
+
+	return m
+}
+
+// addState adds a position entered by consuming a byte of set and returns it.
+func (m *nfa) addState(set *byteSet) int {
+	m.sets = append(m.sets, set)
+	m.follow = append(m.follow, nil)
+	m.accept = append(m.accept, false)
+	return len(m.sets) - 1
+}
+
+// numStates returns the number of automaton states.
+func (m *nfa) numStates() int {
+	return len(m.accept)
+}
+
+// stateSet is a bitset of automaton states.
+type stateSet []uint64
+
+func newStateSet(n int) stateSet {
+	return make(stateSet, (n+63)/64)
+}
+
+func (s stateSet) add(state int) {
+	s[state/64] |= 1 << (state % 64)
+}
+
+func (s stateSet) has(state int) bool {
+	return s[state/64]&(1<<(state%64)) != 0
+}
+
+func (s stateSet) clear() {
+	for i := range s {
+		s[i] = 0
+	}
+}
+
+func (s stateSet) empty() bool {
+	for _, w := range s {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// key returns a string that identifies the set, used to look up cached DFA states.
+func (s stateSet) key() string {
+	b := make([]byte, 0, len(s)*8)
+	for _, w := range s {
+		for i := 0; i < 64; i += 8 {
+			b = append(b, byte(w>>i))
+		}
+	}
+	return string(b)
+}
+
+// start fills set with the initial states.
+func (m *nfa) start(set stateSet) {
+	set.clear()
+	set.add(0)
+}
+
+// step advances every state in cur over byte b and stores the result in next.
+// Unless the pattern is anchored at the start, the start state is added back so
+// that a new match attempt begins at every offset.
+func (m *nfa) step(cur stateSet, b byte, next stateSet) {
+	next.clear()
+	for s := 0; s < m.numStates(); s++ {
+		if !cur.has(s) {
+			continue
+		}
+		for _, t := range m.follow[s] {
+			if !next.has(t) && m.sets[t].has(b) {
+				next.add(t)
+			}
+		}
+	}
+	if !m.anchoredStart {
+		next.add(0)
+	}
+}
+
+// accepts reports whether any state in the set has matched the whole pattern.
+func (m *nfa) accepts(set stateSet) bool {
+	for s, ok := range m.accept {
+		if ok && set.has(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// accepting returns the patterns that have matched in the set: none, or the
+// pattern of the automaton, numbered 0.
+func (m *nfa) accepting(set stateSet) []int {
+	if m.accepts(set) {
+		return onlyPattern
+	}
+	return nil
+}
+
+// onlyPattern lists the single pattern of an nfa.
+var onlyPattern = []int{0}
+
+// match reports whether the pattern matches anywhere in inputText by simulating
+// the automaton over all start positions at once.
+func (m *nfa) match(inputText []byte, b *budget) (bool, error) {
+	cur := newStateSet(m.numStates())
+	m.start(cur)
+	end, err := m.endFrom(inputText, 0, cur, b)
+	return end >= 0, err
+}
+
+// endFrom continues a simulation whose current states are cur at offset pos and
+// returns the offset where the first match to end ends, or -1 if there is none.
+// It is used directly by the DFA when its state cache overflows.
+func (m *nfa) endFrom(inputText []byte, pos int, cur stateSet, b *budget) (int, error) {
+	next := newStateSet(m.numStates())
+	for i := pos; ; i++ {
+		if m.accepts(cur) && (!m.anchoredEnd || i == len(inputText)) {
+			return i, nil
+		}
+		if i >= len(inputText) || cur.empty() {
+			return -1, nil
+		}
+		if err := b.poll(i); err != nil {
+			return -1, err
+		}
+		m.step(cur, inputText[i], next)
+		cur, next = next, cur
+	}
+}
+
+// longestMatch simulates the automaton from offset start and returns the end of
+// the longest match beginning there, or -1. The automaton must be anchored at the
+// start, so that no other match attempt is started along the way.
+func (m *nfa) longestMatch(inputText []byte, start int, b *budget) (int, error) {
+	cur := newStateSet(m.numStates())
+	next := newStateSet(m.numStates())
+	m.start(cur)
+
+	end := -1
+	for i := start; ; i++ {
+		if m.accepts(cur) && (!m.anchoredEnd || i == len(inputText)) {
+			end = i
+		}
+		if i >= len(inputText) || cur.empty() {
+			return end, nil
+		}
+		if err := b.poll(i - start); err != nil {
+			return -1, err
+		}
+		m.step(cur, inputText[i], next)
+		cur, next = next, cur
+	}
+}
+
+// longestReverseFrom continues a backwards simulation whose current states are
+// cur with the bytes from limit up to offset end still to be read. It returns
+// the lowest offset at which the automaton accepts, or start if it accepts at
+// none of them. The automaton must be anchored at the start, as a reversed
+// automaton anchored at the end of a match is.
+func (m *nfa) longestReverseFrom(inputText []byte, end, limit int, cur stateSet, start int, b *budget) (int, error) {
+	next := newStateSet(m.numStates())
+	for i := end; ; i-- {
+		if m.accepts(cur) && (!m.anchoredEnd || i == 0) {
+			start = i
+		}
+		if i <= limit || cur.empty() {
+			return start, nil
+		}
+		if err := b.poll(end - i); err != nil {
+			return -1, err
+		}
+		m.step(cur, inputText[i-1], next)
+		cur, next = next, cur
+	}
+}
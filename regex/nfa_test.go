@@ -0,0 +1,39 @@
+package regex
+
+import (
+	"testing"
+)
+
+func TestNFALongestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		start   int
+		want    int
+	}{
+		{pattern: "a+b?", input: "aaab", start: 0, want: 4},
+		{pattern: "a+b?", input: "xaaa", start: 1, want: 4},
+		{pattern: "a+b?", input: "xaaa", start: 0, want: -1},
+		{pattern: "a?", input: "b", start: 0, want: 0},
+		{pattern: "\\d+\\w?", input: "12ab", start: 0, want: 3},
+		{pattern: "a+$", input: "aab", start: 0, want: -1},
+		{pattern: "a+$", input: "baa", start: 1, want: 3},
+		{pattern: ".+", input: "ab\ncd", start: 0, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.input, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePattern() error = %v", err)
+			}
+			got, err := p.anchored.longestMatch([]byte(tt.input), tt.start, unlimited())
+			if err != nil {
+				t.Fatalf("longestMatch() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("longestMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
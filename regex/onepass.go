@@ -0,0 +1,77 @@
+package regex
+
+// onePass matches patterns that are anchored at the start and unambiguous: from
+// every state, each byte leads to at most one next state. Such a pattern can be
+// run as a DFA whose states are the position automaton's states themselves, and
+// since the path through the pattern is unique, the match position falls out of
+// the single pass without any backtracking.
+type onePass struct {
+	next        []int // next[s*256+b] is the state entered from s on byte b, or -1
+	accept      []bool
+	anchoredEnd bool
+}
+
+// newOnePass builds a one-pass matcher from the position automaton. It returns
+// nil if the pattern isn't anchored at the start or isn't one-pass.
+func newOnePass(m *nfa) *onePass {
+	if !m.anchoredStart {
+		return nil
+	}
+
+	op := &onePass{
+		next:        make([]int, m.numStates()*256),
+		accept:      m.accept,
+		anchoredEnd: m.anchoredEnd,
+	}
+	for i := range op.next {
+		op.next[i] = -1
+	}
+
+	for s, follow := range m.follow {
+		for _, t := range follow {
+			for b := 0; b < 256; b++ {
+				if !m.sets[t].has(byte(b)) {
+					continue
+				}
+				if op.next[s*256+b] >= 0 {
+					// Two ways forward on the same byte: not one-pass
+					return nil
+				}
+				op.next[s*256+b] = t
+			}
+		}
+	}
+
+	return op
+}
+
+// find returns the position of the match as a pair of indexes into inputText, as
+// in regexp.FindIndex, or nil if there is no match.
+//
+// Quantifiers are greedy: the automaton keeps consuming bytes for as long as it
+// can and the match ends at the last accepting state it passed through.
+func (op *onePass) find(inputText []byte, b *budget) ([]int, error) {
+	s := 0
+	end := -1
+	if op.accept[s] {
+		end = 0
+	}
+
+	for i := 0; i < len(inputText); i++ {
+		if err := b.poll(i); err != nil {
+			return nil, err
+		}
+		s = op.next[s*256+int(inputText[i])]
+		if s < 0 {
+			break
+		}
+		if op.accept[s] {
+			end = i + 1
+		}
+	}
+
+	if end < 0 || (op.anchoredEnd && end != len(inputText)) {
+		return nil, nil
+	}
+	return []int{0, end}, nil
+}
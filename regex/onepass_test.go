@@ -0,0 +1,73 @@
+package regex
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestNewOnePass(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"^\\d\\d\\d-\\w+$", true},
+		{"^apple", true},
+		{"^[abc]+d?$", true},
+		{"^a?b?c$", true},
+		{"^$", true},
+		{"\\d\\d\\d-\\w+$", false}, // not anchored at the start
+		{"^\\w+\\d$", false},       // \w+ and \d both take digits
+		{"^a?a", false},            // a can be consumed by either token
+		{"^a+a", false},
+		{"^.+x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePattern() error = %v", err)
+			}
+			if got := p.onePass != nil; got != tt.want {
+				t.Errorf("one-pass = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnePassFind(t *testing.T) {
+	tests := []struct {
+		pattern string
+		inputs  []string
+	}{
+		{"^\\d\\d\\d-\\w+$", []string{"123-abc", "123-", "12-abc", "123-abc!", "123-a_b_c"}},
+		{"^\\d\\d\\d-\\w+", []string{"123-abc!", "123-", "123-abc def"}},
+		{"^[abc]+d?", []string{"abcd", "abcdd", "aaa", "d", ""}},
+		{"^a?b?c", []string{"c", "ac", "bc", "abc", "abcc", "ab"}},
+		{"^x?", []string{"", "x", "xx", "y"}},
+		{"^colou?r$", []string{"color", "colour", "colouur"}},
+		{"^apple", []string{"apple pie", "green apple", ""}},
+	}
+
+	for _, tt := range tests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		if p.onePass == nil {
+			t.Fatalf("pattern %q is not one-pass", tt.pattern)
+		}
+		re := regexp.MustCompile(tt.pattern)
+		for _, input := range tt.inputs {
+			want := re.FindStringIndex(input)
+			got, err := p.onePass.find([]byte(input), unlimited())
+			if err != nil {
+				t.Fatalf("find() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("find(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package regex
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// program is a compiled pattern ready to be matched against input lines.
+//
+// Everything in a program is read-only once compilePattern returns, except for
+// the lazy DFA caches, which are handed out to one search at a time by their
+// pools. A program can therefore be used by many goroutines at once.
+type program struct {
+	tokens        []token
+	anchoredStart bool // pattern started with ^
+	anchoredEnd   bool // pattern ended with $
+	prefilter     *prefilter
+	limits        matchLimits
+	longest       bool // report leftmost-longest (POSIX) matches instead of leftmost-first
+
+	// Engines for answering whether a line matches. onePass is nil unless the
+	// pattern is anchored and unambiguous, reverse is nil unless the pattern ends
+	// with $ or a literal worth searching for, shiftAnd is nil for patterns too
+	// long for it or with multibyte tokens, and the lazy DFA handles everything
+	// else.
+	onePass  *onePass
+	reverse  *reverseSearcher
+	shiftAnd *shiftAnd
+	dfas     *dfaPool
+
+	// anchored is the position automaton anchored at the start, used to find the
+	// longest match from a given offset, and backward the reversed automaton
+	// anchored at the end, used to find where the matches ending at an offset
+	// start.
+	anchored *nfa
+	backward *dfaPool
+}
+
+// compilePattern parses the pattern and prepares the automata used to match it.
+//
+// A leading ^ and an unescaped trailing $ are treated as anchors; everywhere else
+// they are ordinary characters.
+func compilePattern(pattern string) (*program, error) {
+	p := &program{limits: matchLimits{maxSteps: DefaultBacktrackLimit}}
+
+	tokens, anchoredStart, anchoredEnd, err := parsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	p.tokens = tokens
+	p.anchoredStart = anchoredStart
+	p.anchoredEnd = anchoredEnd
+
+	p.prefilter = newPrefilter(tokens)
+	m := newNFA(tokens, p.anchoredStart, p.anchoredEnd)
+	p.onePass = newOnePass(m)
+	p.reverse = newReverseSearcher(tokens, p.anchoredStart, p.anchoredEnd, p.prefilter.prefix)
+	p.shiftAnd = newShiftAnd(tokens, p.anchoredStart, p.anchoredEnd)
+	p.dfas = newDFAPool(m)
+	p.anchored = m
+	if !p.anchoredStart {
+		p.anchored = newNFA(tokens, true, p.anchoredEnd)
+	}
+	if p.reverse != nil {
+		p.backward = p.reverse.dfas
+	} else {
+		p.backward = newDFAPool(newNFA(reverseTokens(tokens), true, p.anchoredStart))
+	}
+
+	return p, nil
+}
+
+// parsePattern strips the anchors off the pattern and parses the rest into
+// tokens.
+func parsePattern(pattern string) (tokens []token, anchoredStart, anchoredEnd bool, err error) {
+	if strings.HasPrefix(pattern, "^") {
+		anchoredStart = true
+		pattern = pattern[1:] // Remove leading ^, ^apple -> apple
+	}
+	if hasEndAnchor(pattern) {
+		anchoredEnd = true
+		pattern = pattern[:len(pattern)-1] // Remove trailing $, apple$ -> apple
+	}
+
+	tokens, err = parseTokens(pattern)
+	return tokens, anchoredStart, anchoredEnd, err
+}
+
+// hasEndAnchor reports whether the pattern ends with a $ that isn't escaped.
+func hasEndAnchor(pattern string) bool {
+	if !strings.HasSuffix(pattern, "$") {
+		return false
+	}
+	// An odd number of backslashes before the $ means the $ itself is escaped
+	backslashes := 0
+	for i := len(pattern) - 2; i >= 0 && pattern[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// match reports whether the pattern matches anywhere in inputText.
+// Only whether a match exists is needed here. Anchored unambiguous patterns run
+// on the one-pass matcher, end-anchored patterns and patterns with a literal
+// suffix are scanned backwards, short patterns run on the bit-parallel engine and
+// the rest on the lazy DFA, starting at the first offset the prefilter can't
+// rule out. A backwards scan that runs over too many occurrences of its suffix
+// gives up and leaves the line to the forward engines.
+//
+// It returns an error if the search runs past the pattern's deadline or ctx is
+// done.
+func (p *program) match(ctx context.Context, inputText []byte) (bool, error) {
+	// The engines below check the clock as they go, but the prefilter can
+	// settle the search without running any of them
+	b := newBudget(ctx, p.limits)
+	if err := b.expired(); err != nil {
+		return false, err
+	}
+	start, ok := p.prefilter.candidate(inputText, p.anchoredStart)
+	if !ok {
+		return false, nil
+	}
+	if p.prefilter.literal && !p.anchoredEnd {
+		// Finding the literal was the whole match
+		return true, nil
+	}
+
+	if p.onePass != nil {
+		loc, err := p.onePass.find(inputText[start:], b)
+		return loc != nil, err
+	}
+	if p.reverse != nil {
+		ok, err := p.reverse.match(inputText[start:], b)
+		if err != errReverseWork {
+			return ok, err
+		}
+		// Too many occurrences of the suffix: scan forwards instead
+	}
+	switch {
+	case p.shiftAnd != nil:
+		return p.shiftAnd.match(inputText[start:], b)
+	default:
+		d := p.dfas.get()
+		defer p.dfas.put(d)
+		return d.match(inputText[start:], b)
+	}
+}
+
+// find returns the leftmost match in inputText as a slice of index pairs, in the
+// layout of regexp.FindSubmatchIndex: the first pair is the whole match and the
+// following pairs are the submatches. Patterns have no groups yet, so the pair
+// for the whole match is all there is. It returns nil if there is no match.
+//
+// By default the match is leftmost-first, as in Perl and the regexp package: of
+// the matches starting at the leftmost position, the one the greedy backtracker
+// finds first. With longest set it is leftmost-longest, as POSIX specifies: the
+// longest of those matches.
+func (p *program) find(ctx context.Context, inputText []byte) ([]int, error) {
+	return p.findAt(ctx, inputText, 0, nil)
+}
+
+// findAt is like find but only reports matches starting at pos or later. Anchors
+// still refer to the whole of inputText.
+//
+// Whether there is a match at all is settled first by the fast engines, so lines
+// that don't match never reach the backtracker. Where the leftmost match starts
+// is found by linear scans as well: the lazy DFA finds where the first match to
+// end ends, and the reversed automaton, read backwards from there, finds the
+// leftmost start of the matches ending there, which is the leftmost start of
+// all matches since every match is a sequence of whole tokens: single bytes, or
+// characters of valid UTF-8, whose boundaries don't depend on where decoding
+// starts. The backtracker then only runs from that start, to find where the
+// match ends.
+//
+// bt may be nil; callers searching the same input repeatedly pass the same
+// backtracker so that the failures it has memoized carry over from one search
+// to the next. Each call is a search of its own as far as the step limit goes,
+// so the backtracker's step count starts again from zero.
+func (p *program) findAt(ctx context.Context, inputText []byte, pos int, bt *backtracker) ([]int, error) {
+	if p.anchoredStart && pos > 0 {
+		return nil, nil
+	}
+	if ok, err := p.match(ctx, inputText[pos:]); !ok || err != nil {
+		return nil, err
+	}
+
+	if p.onePass != nil {
+		// The path through the pattern is unique, so the first match is the longest
+		return p.onePass.find(inputText, newBudget(ctx, p.limits))
+	}
+	if bt == nil {
+		bt = newBacktracker(inputText, p.tokens, p.anchoredEnd, newBudget(ctx, p.limits))
+	} else {
+		bt.budget.reset()
+	}
+	start, err := p.leftmostStart(inputText, pos, bt.budget)
+	if start < 0 || err != nil {
+		return nil, err
+	}
+	end, err := bt.matchFromPosition(start)
+	if end < 0 || err != nil {
+		return nil, err
+	}
+	if !p.longest {
+		return []int{start, end}, nil
+	}
+
+	// Both semantics agree on where the match starts; extend it as far as possible
+	end, err = p.anchored.longestMatch(inputText, start, bt.budget)
+	if err != nil {
+		return nil, err
+	}
+	return []int{start, end}, nil
+}
+
+// leftmostStart returns the offset, pos or later, where the leftmost match in
+// inputText starts, or -1 if there is none.
+func (p *program) leftmostStart(inputText []byte, pos int, b *budget) (int, error) {
+	if p.anchoredStart {
+		return 0, nil
+	}
+	from, ok := p.prefilter.candidate(inputText[pos:], false)
+	if !ok {
+		return -1, nil
+	}
+	from += pos
+
+	d := p.dfas.get()
+	end, err := d.earliestEnd(inputText[from:], b)
+	p.dfas.put(d)
+	if end < 0 || err != nil {
+		return -1, err
+	}
+
+	r := p.backward.get()
+	defer p.backward.put(r)
+	return r.longestReverse(inputText, from+end, from, b)
+}
+
+// backtrack returns the leftmost match in inputText, like find, using only the
+// backtracking engine.
+func (p *program) backtrack(ctx context.Context, inputText []byte) ([]int, error) {
+	return p.backtrackFrom(newBacktracker(inputText, p.tokens, p.anchoredEnd, newBudget(ctx, p.limits)), 0)
+}
+
+// backtrackFrom runs the backtracking engine from every start position from
+// start onwards until one matches. It takes quadratic time on some inputs and
+// is only used to check the other engines against.
+//
+// It returns ErrBacktrackLimit or ErrTimeout if the search is aborted.
+func (p *program) backtrackFrom(bt *backtracker, start int) ([]int, error) {
+	inputText := bt.inputText
+
+	// Try matching from each position in the inputText
+	for ; start <= len(inputText); start++ {
+		end, err := bt.matchFromPosition(start)
+		if err != nil {
+			return nil, err
+		}
+		if end >= 0 {
+			return []int{start, end}, nil
+		}
+		if p.anchoredStart {
+			break
+		}
+	}
+
+	return nil, nil
+}
+
+// forEachMatch calls fn with every successive non-overlapping match in
+// inputText, in the layout returned by find, until fn returns false.
+//
+// As in the regexp package, after an empty match the search resumes one rune
+// further on, and an empty match right where the previous match ended is not
+// reported, so the same offset is never reported twice.
+func (p *program) forEachMatch(ctx context.Context, inputText []byte, fn func(loc []int) bool) error {
+	bt := newBacktracker(inputText, p.tokens, p.anchoredEnd, newBudget(ctx, p.limits))
+	prevEnd := -1
+	for pos := 0; pos <= len(inputText); {
+		loc, err := p.findAt(ctx, inputText, pos, bt)
+		if err != nil {
+			return err
+		}
+		if loc == nil {
+			break
+		}
+
+		accept := true
+		if loc[1] == loc[0] {
+			// Empty match: step over the next rune so the search makes progress
+			if loc[0] == prevEnd {
+				accept = false
+			}
+			if loc[1] < len(inputText) {
+				_, width := utf8.DecodeRune(inputText[loc[1]:])
+				pos = loc[1] + width
+			} else {
+				pos = loc[1] + 1
+			}
+		} else {
+			pos = loc[1]
+		}
+		prevEnd = loc[1]
+
+		if accept && !fn(loc) {
+			break
+		}
+	}
+	return nil
+}
+
+// findAll returns up to n successive non-overlapping matches in inputText, or all
+// of them if n is negative. It returns nil if there is no match.
+func (p *program) findAll(ctx context.Context, inputText []byte, n int) ([][]int, error) {
+	var matches [][]int
+	if n == 0 {
+		return nil, nil
+	}
+	err := p.forEachMatch(ctx, inputText, func(loc []int) bool {
+		matches = append(matches, loc)
+		return n < 0 || len(matches) < n
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
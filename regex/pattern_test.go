@@ -0,0 +1,225 @@
+package regex
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// engineTests are shared by the tests of every matching engine. The patterns only
+// use syntax that means the same thing to the standard regexp package, which is
+// used as the reference, and the inputs are valid UTF-8.
+var engineTests = []struct {
+	pattern string
+	inputs  []string
+}{
+	{"a", []string{"", "a", "b", "banana"}},
+	{"abc", []string{"abc", "xyzabcdef", "ab", "aabbcc"}},
+	{"\\d", []string{"hello123", "hello", ""}},
+	{"\\d\\d\\d apple", []string{"100 apples", "1 apple", "x 123 apple"}},
+	{"\\w+", []string{"!!!", "hello", "__", ""}},
+	{"[abc]+d", []string{"abcd", "aaabbbcccd", "d", "xd", "abc"}},
+	{"[^abc]", []string{"cat", "cab", "", "abcabc", "é"}},
+	{"ca+ts", []string{"cats", "caaats", "cts", "ca"}},
+	{"ca+at", []string{"caaats", "caats", "cat", "caaaa"}},
+	{"a+b+c", []string{"aaabbbc", "abc", "ac", "aabbbbbx"}},
+	{"colou?r", []string{"color", "colour", "colouur", "colr"}},
+	{"a?", []string{"", "a", "b"}},
+	{"a?b?c?", []string{"", "xyz"}},
+	{"d.g", []string{"dog", "d\ng", "dg", "xd@gx", "dég", "d日本g"}},
+	{".+", []string{"", "\n", "x"}},
+	{"a.?b", []string{"ab", "acb", "accb"}},
+	{"x?y+z?", []string{"y", "xz", "xyyz", "zzz"}},
+	{"^apple", []string{"apple pie", "green apple", "apple", ""}},
+	{"^\\d+", []string{"123abc", "abc123", ""}},
+	{"apple$", []string{"green apple", "apple pie", "apple", "apple\n"}},
+	{"\\w+s$", []string{"3 dogs", "dogs!", "s", "ss"}},
+	{"^apple$", []string{"apple", "apple pie", "green apple", "appl"}},
+	{"^a+$", []string{"aaaa", "aaab", "", "a"}},
+	{"^a?$", []string{"", "a", "aa"}},
+	{"^$", []string{"", "x"}},
+	{"^", []string{"", "x"}},
+	{"$", []string{"", "x"}},
+	{"café", []string{"un café", "cafe", "cafécafé"}},
+	{"^.$", []string{"é", "😀", "ab"}},
+	{"^.+$", []string{"日本語", "a\nb"}},
+	{"caf[éè]", []string{"un café", "cafè", "cafe", "caf"}},
+	{"[^é]", []string{"é", "éa", "ééé", ""}},
+	{"[αβ]+x?", []string{"ααβx", "xαβ", "γ"}},
+	{"[日本]+語", []string{"日本語", "本本日語x", "語"}},
+	{"a[^é日]+b", []string{"aéb", "axyzb", "a日b", "aπ€b"}},
+	{"[é]?e", []string{"ée", "e", "éé"}},
+	{"^[éa]+$", []string{"éaé", "éb", ""}},
+	{"[é😀]+ the end$", []string{"😀é the end", "x the end"}},
+}
+
+// referenceMatch reports whether the standard regexp package finds a match.
+func referenceMatch(t *testing.T, pattern, input string) bool {
+	t.Helper()
+	return regexp.MustCompile(pattern).MatchString(input)
+}
+
+// mustMatch returns a function that unwraps the result of an engine's match,
+// failing the test if the engine returned an error.
+func mustMatch(t *testing.T) func(bool, error) bool {
+	return func(ok bool, err error) bool {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("match error = %v", err)
+		}
+		return ok
+	}
+}
+
+// unlimited returns a budget with no limits.
+func unlimited() *budget {
+	return newBudget(context.Background(), matchLimits{})
+}
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		anchoredStart bool
+		anchoredEnd   bool
+		numTokens     int
+		wantErr       bool
+	}{
+		{name: "no anchors", pattern: "abc", numTokens: 3},
+		{name: "start anchor", pattern: "^abc", anchoredStart: true, numTokens: 3},
+		{name: "end anchor", pattern: "abc$", anchoredEnd: true, numTokens: 3},
+		{name: "both anchors", pattern: "^abc$", anchoredStart: true, anchoredEnd: true, numTokens: 3},
+		{name: "anchors only", pattern: "^$", anchoredStart: true, anchoredEnd: true, numTokens: 0},
+		{name: "$ after literal backslash is an anchor", pattern: "a\\\\$", anchoredEnd: true, numTokens: 2},
+		{name: "escaped $ is not an anchor", pattern: "a\\$", numTokens: 2},
+		{name: "escaped ^ is not an anchor", pattern: "\\^a", numTokens: 2},
+		{name: "^ in the middle is a literal", pattern: "a^b", numTokens: 3},
+		{name: "parse error", pattern: "[abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compilePattern() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.anchoredStart != tt.anchoredStart || p.anchoredEnd != tt.anchoredEnd {
+				t.Errorf("anchors = (%v, %v), want (%v, %v)", p.anchoredStart, p.anchoredEnd, tt.anchoredStart, tt.anchoredEnd)
+			}
+			if len(p.tokens) != tt.numTokens {
+				t.Errorf("len(tokens) = %d, want %d", len(p.tokens), tt.numTokens)
+			}
+		})
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	for _, tt := range engineTests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		for _, input := range tt.inputs {
+			want := referenceMatch(t, tt.pattern, input)
+			if got := mustMatch(t)(p.match(context.Background(), []byte(input))); got != want {
+				t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
+
+func TestPatternFind(t *testing.T) {
+	for _, tt := range engineTests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		re := regexp.MustCompile(tt.pattern)
+		for _, input := range tt.inputs {
+			want := re.FindStringIndex(input)
+			got, err := p.find(context.Background(), []byte(input))
+			if err != nil {
+				t.Fatalf("find() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("find(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
+
+func TestPatternFindLongest(t *testing.T) {
+	for _, tt := range engineTests {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		p.longest = true
+		re := regexp.MustCompile(tt.pattern)
+		re.Longest()
+		for _, input := range tt.inputs {
+			want := re.FindStringIndex(input)
+			got, err := p.find(context.Background(), []byte(input))
+			if err != nil {
+				t.Fatalf("find() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("find(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
+
+func TestPatternFindAll(t *testing.T) {
+	cases := append(engineTests[:len(engineTests):len(engineTests)], []struct {
+		pattern string
+		inputs  []string
+	}{
+		{"a?", []string{"ab", "baaab", "aaa"}},
+		{"x?", []string{"héllo", "日本x語"}},
+		{"\\d+", []string{"1 22 333", "a1b22c"}},
+		{"\\w?$", []string{"ab", "a b", ""}},
+		{"^\\w", []string{"abc", " abc"}},
+	}...)
+
+	for _, tt := range cases {
+		p, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		re := regexp.MustCompile(tt.pattern)
+		for _, input := range tt.inputs {
+			want := re.FindAllStringIndex(input, -1)
+			got, err := p.findAll(context.Background(), []byte(input), -1)
+			if err != nil {
+				t.Fatalf("findAll() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("findAll(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
+
+func TestPatternFindAllLimit(t *testing.T) {
+	p, err := compilePattern("\\d")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	input := []byte("1 2 3 4")
+
+	for _, n := range []int{0, 1, 3, 10} {
+		got, err := p.findAll(context.Background(), input, n)
+		if err != nil {
+			t.Fatalf("findAll() error = %v", err)
+		}
+		want := min(n, 4)
+		if len(got) != want {
+			t.Errorf("findAll(n=%d) returned %d matches, want %d", n, len(got), want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package regex
+
+import "bytes"
+
+// prefilter holds literal strings that every match must contain. They are found
+// with bytes.Index, which is much faster than running an engine over the line,
+// so lines that can't match are rejected before any engine runs.
+type prefilter struct {
+	prefix   []byte // literal every match starts with, if any
+	required []byte // longest literal every match contains, if any
+	literal  bool   // the whole pattern is the literal in prefix
+}
+
+// newPrefilter analyzes the tokens for mandatory literal strings.
+//
+// A run of unquantified literal tokens must appear verbatim in any match. A
+// literal with + contributes one copy of its character to the run before it and
+// starts the run after it, since "a+b" always contains "ab".
+func newPrefilter(tokens []token) *prefilter {
+	f := &prefilter{}
+
+	var runs [][]byte
+	var run []byte
+	runStart := 0
+	closeRun := func(next int) {
+		if len(run) > 0 {
+			if runStart == 0 && f.prefix == nil {
+				f.prefix = run
+			}
+			runs = append(runs, run)
+		}
+		run = nil
+		runStart = next
+	}
+
+	for i, tok := range tokens {
+		if tok.Type != literal {
+			closeRun(i + 1)
+			continue
+		}
+		switch tok.Quantifier {
+		case none:
+			run = append(run, tok.Value[0])
+		case oneOrMore:
+			run = append(run, tok.Value[0])
+			closeRun(i)
+			run = []byte{tok.Value[0]}
+		default:
+			closeRun(i + 1)
+		}
+	}
+	closeRun(len(tokens))
+
+	for _, r := range runs {
+		if len(r) > len(f.required) {
+			f.required = r
+		}
+	}
+	f.literal = len(runs) == 1 && len(f.prefix) == len(tokens)
+
+	return f
+}
+
+// candidate returns the first offset at which a match can start. It returns false
+// if the line can't contain a match at all.
+func (f *prefilter) candidate(inputText []byte, anchoredStart bool) (int, bool) {
+	if f.required != nil && !bytes.Contains(inputText, f.required) {
+		return 0, false
+	}
+	if f.prefix == nil {
+		return 0, true
+	}
+
+	if anchoredStart {
+		return 0, bytes.HasPrefix(inputText, f.prefix)
+	}
+	// Skip straight to the first place the prefix occurs
+	i := bytes.Index(inputText, f.prefix)
+	return i, i >= 0
+}
@@ -0,0 +1,73 @@
+package regex
+
+import (
+	"testing"
+)
+
+func TestNewPrefilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		prefix   string
+		required string
+		literal  bool
+	}{
+		{name: "plain literal", pattern: "apple", prefix: "apple", required: "apple", literal: true},
+		{name: "literal prefix", pattern: "log\\d+", prefix: "log", required: "log"},
+		{name: "longest run wins", pattern: "a\\d error: \\w+", prefix: "a", required: " error: "},
+		{name: "literal + joins both runs", pattern: "xa+b", prefix: "xa", required: "xa"},
+		{name: "literal + at the start", pattern: "a+bcd", prefix: "a", required: "abcd"},
+		{name: "optional literal breaks the run", pattern: "colou?r", prefix: "colo", required: "colo"},
+		{name: "no literals", pattern: "\\d+[abc]", prefix: "", required: ""},
+		{name: "leading class has no prefix", pattern: ".apple", prefix: "", required: "apple"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := parseTokens(tt.pattern)
+			if err != nil {
+				t.Fatalf("parseTokens() error = %v", err)
+			}
+			f := newPrefilter(tokens)
+			if string(f.prefix) != tt.prefix {
+				t.Errorf("prefix = %q, want %q", f.prefix, tt.prefix)
+			}
+			if string(f.required) != tt.required {
+				t.Errorf("required = %q, want %q", f.required, tt.required)
+			}
+			if f.literal != tt.literal {
+				t.Errorf("literal = %v, want %v", f.literal, tt.literal)
+			}
+		})
+	}
+}
+
+func TestPrefilterCandidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		line      string
+		wantStart int
+		wantOK    bool
+	}{
+		{name: "jumps to prefix", pattern: "log\\d", line: "xxxxlog1", wantStart: 4, wantOK: true},
+		{name: "missing required literal", pattern: "\\d+ apple", line: "100 pears", wantOK: false},
+		{name: "missing prefix", pattern: "log\\d", line: "lo1", wantOK: false},
+		{name: "anchored prefix present", pattern: "^log\\d", line: "log1", wantStart: 0, wantOK: true},
+		{name: "anchored prefix elsewhere", pattern: "^log\\d", line: "xlog1", wantOK: false},
+		{name: "nothing to filter on", pattern: "\\d", line: "abc", wantStart: 0, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePattern() error = %v", err)
+			}
+			start, ok := p.prefilter.candidate([]byte(tt.line), p.anchoredStart)
+			if ok != tt.wantOK || (ok && start != tt.wantStart) {
+				t.Errorf("candidate() = (%d, %v), want (%d, %v)", start, ok, tt.wantStart, tt.wantOK)
+			}
+		})
+	}
+}
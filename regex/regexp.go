@@ -0,0 +1,238 @@
+// Package regex implements the pattern matcher behind mygrep.
+//
+// The syntax is the subset of extended regular expressions the parser
+// understands: literal characters, \d, \w, \\, character classes [abc] and
+// [^abc], the dot, the + and ? quantifiers, and the anchors ^ and $ at the start
+// and end of the pattern.
+//
+// Literal characters match their bytes, and \d and \w single ASCII bytes. The
+// dot and character classes match whole UTF-8 encoded characters: they never
+// match part of a multibyte character, nor a byte that isn't valid UTF-8,
+// unless the body of the class itself isn't valid UTF-8, in which case it lists
+// bytes.
+//
+// Searches are bounded by a backtracking step limit and an optional deadline,
+// so unlike the standard regexp package every search method also returns an
+// error, which is ErrBacktrackLimit or ErrTimeout when a search is aborted. The
+// methods ending in Context also stop when their context is done and then
+// return the context's error.
+package regex
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Regexp is a compiled pattern.
+//
+// A Regexp is safe for concurrent use by multiple goroutines, except for the
+// configuration methods Longest, SetBacktrackLimit and SetDeadline, which must
+// be called before the Regexp is shared.
+type Regexp struct {
+	expr string
+	prog *program
+}
+
+// Compile parses a pattern and returns a Regexp that can be used to match
+// against text.
+func Compile(expr string) (*Regexp, error) {
+	prog, err := compilePattern(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Regexp{expr: expr, prog: prog}, nil
+}
+
+// MustCompile is like Compile but panics if the pattern cannot be parsed.
+func MustCompile(expr string) *Regexp {
+	re, err := Compile(expr)
+	if err != nil {
+		panic(`regex: Compile(` + strconv.Quote(expr) + `): ` + err.Error())
+	}
+	return re
+}
+
+// String returns the source text used to compile the Regexp.
+func (re *Regexp) String() string {
+	return re.expr
+}
+
+// Longest makes future searches prefer leftmost-longest (POSIX) matches over the
+// default leftmost-first matches.
+func (re *Regexp) Longest() {
+	re.prog.longest = true
+}
+
+// SetBacktrackLimit sets how many backtracking steps a single search may take.
+// A step explores one pair of pattern position and input offset; returning to
+// a pair already known to fail is free. Zero means no limit. The default is
+// DefaultBacktrackLimit.
+func (re *Regexp) SetBacktrackLimit(n int) {
+	re.prog.limits.maxSteps = n
+}
+
+// SetDeadline makes searches still running at t fail with ErrTimeout. The zero
+// time means no deadline, which is the default.
+func (re *Regexp) SetDeadline(t time.Time) {
+	re.prog.limits.deadline = t
+}
+
+// Match reports whether b contains any match of the pattern.
+func (re *Regexp) Match(b []byte) (bool, error) {
+	return re.prog.match(context.Background(), b)
+}
+
+// MatchContext is like Match but gives up with ctx.Err() once ctx is done.
+func (re *Regexp) MatchContext(ctx context.Context, b []byte) (bool, error) {
+	return re.prog.match(ctx, b)
+}
+
+// Find returns the text of the leftmost match in b, or nil if there is none.
+func (re *Regexp) Find(b []byte) ([]byte, error) {
+	loc, err := re.prog.find(context.Background(), b)
+	if loc == nil || err != nil {
+		return nil, err
+	}
+	return b[loc[0]:loc[1]:loc[1]], nil
+}
+
+// FindIndex returns a two-element slice of integers defining the location of the
+// leftmost match in b, or nil if there is none. The match is b[loc[0]:loc[1]].
+func (re *Regexp) FindIndex(b []byte) ([]int, error) {
+	return re.prog.find(context.Background(), b)
+}
+
+// FindIndexContext is like FindIndex but gives up with ctx.Err() once ctx is
+// done.
+func (re *Regexp) FindIndexContext(ctx context.Context, b []byte) ([]int, error) {
+	return re.prog.find(ctx, b)
+}
+
+// FindSubmatch returns the text of the leftmost match in b and of its
+// submatches, or nil if there is none. Patterns have no groups, so the result
+// only holds the whole match.
+func (re *Regexp) FindSubmatch(b []byte) ([][]byte, error) {
+	loc, err := re.prog.find(context.Background(), b)
+	if loc == nil || err != nil {
+		return nil, err
+	}
+	return submatches(b, loc), nil
+}
+
+// FindSubmatchIndex returns the index pairs of the leftmost match in b and of
+// its submatches, or nil if there is none.
+func (re *Regexp) FindSubmatchIndex(b []byte) ([]int, error) {
+	return re.prog.find(context.Background(), b)
+}
+
+// FindAll returns the text of up to n successive non-overlapping matches in b,
+// or of all of them if n is negative. It returns nil if there is no match.
+func (re *Regexp) FindAll(b []byte, n int) ([][]byte, error) {
+	locs, err := re.prog.findAll(context.Background(), b, n)
+	if locs == nil || err != nil {
+		return nil, err
+	}
+	matches := make([][]byte, len(locs))
+	for i, loc := range locs {
+		matches[i] = b[loc[0]:loc[1]:loc[1]]
+	}
+	return matches, nil
+}
+
+// FindAllIndex is like FindAll but returns the location of each match.
+func (re *Regexp) FindAllIndex(b []byte, n int) ([][]int, error) {
+	return re.prog.findAll(context.Background(), b, n)
+}
+
+// FindAllIndexContext is like FindAllIndex but gives up with ctx.Err() once ctx
+// is done.
+func (re *Regexp) FindAllIndexContext(ctx context.Context, b []byte, n int) ([][]int, error) {
+	return re.prog.findAll(ctx, b, n)
+}
+
+// ForEachMatch calls fn with the location of every successive non-overlapping
+// match in b, in the layout returned by FindSubmatchIndex, until fn returns
+// false. After an empty match the search resumes one rune further on, and an
+// empty match right where the previous match ended is not reported.
+func (re *Regexp) ForEachMatch(b []byte, fn func(loc []int) bool) error {
+	return re.prog.forEachMatch(context.Background(), b, fn)
+}
+
+// ForEachMatchContext is like ForEachMatch but gives up with ctx.Err() once ctx
+// is done.
+func (re *Regexp) ForEachMatchContext(ctx context.Context, b []byte, fn func(loc []int) bool) error {
+	return re.prog.forEachMatch(ctx, b, fn)
+}
+
+// A Finder searches one input for matches of a Regexp that start at or after a
+// given offset. It keeps the failures it has memoized from one search to the
+// next, so a series of searches of the same input costs about as much as a
+// single ForEachMatch over it. A Finder is not safe for concurrent use.
+type Finder struct {
+	prog  *program
+	ctx   context.Context
+	input []byte
+	bt    *backtracker // created by the first search that needs it
+}
+
+// NewFinder returns a Finder for the input b.
+func (re *Regexp) NewFinder(b []byte) *Finder {
+	return re.NewFinderContext(context.Background(), b)
+}
+
+// NewFinderContext is like NewFinder but its searches give up with ctx.Err()
+// once ctx is done.
+func (re *Regexp) NewFinderContext(ctx context.Context, b []byte) *Finder {
+	return &Finder{prog: re.prog, ctx: ctx, input: b}
+}
+
+// FindIndexAt returns the location of the leftmost match in the input that
+// starts at pos or later, in the layout returned by FindSubmatchIndex, or nil
+// if there is none. Unlike searching a slice of the input, anchors still refer
+// to the whole input: a pattern starting with ^ only matches at pos 0.
+func (f *Finder) FindIndexAt(pos int) ([]int, error) {
+	p := f.prog
+	if f.bt == nil {
+		// Most inputs don't match, and those never need the backtracker
+		if p.anchoredStart && pos > 0 {
+			return nil, nil
+		}
+		if ok, err := p.match(f.ctx, f.input[pos:]); !ok || err != nil {
+			return nil, err
+		}
+		f.bt = newBacktracker(f.input, p.tokens, p.anchoredEnd, newBudget(f.ctx, p.limits))
+	}
+	return p.findAt(f.ctx, f.input, pos, f.bt)
+}
+
+// submatches slices b at every index pair in loc; unmatched pairs give nil.
+func submatches(b []byte, loc []int) [][]byte {
+	subs := make([][]byte, len(loc)/2)
+	for i := range subs {
+		if loc[2*i] >= 0 {
+			subs[i] = b[loc[2*i]:loc[2*i+1]:loc[2*i+1]]
+		}
+	}
+	return subs
+}
+
+// LiteralPrefix returns a literal string that must begin any match of the
+// pattern, and whether that literal is the entire pattern.
+func (re *Regexp) LiteralPrefix() (prefix string, complete bool) {
+	p := re.prog
+	complete = (p.prefilter.literal || len(p.tokens) == 0) && !p.anchoredStart && !p.anchoredEnd
+	return string(p.prefilter.prefix), complete
+}
+
+// NumSubexp returns the number of parenthesized subexpressions in the pattern.
+// The syntax has no groups, so it is always zero.
+func (re *Regexp) NumSubexp() int {
+	return 0
+}
+
+// SubexpNames returns the names of the parenthesized subexpressions, with the
+// whole match at index 0, which is always unnamed.
+func (re *Regexp) SubexpNames() []string {
+	return []string{""}
+}
@@ -0,0 +1,1105 @@
+package regex
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMatchLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    []byte
+		pattern string
+		want    bool
+		wantErr bool
+	}{
+		// \d 패턴 테스트
+		{
+			name:    "\\d matches digit",
+			line:    []byte("hello123"),
+			pattern: "\\d",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\d no match",
+			line:    []byte("hello"),
+			pattern: "\\d",
+			want:    false,
+			wantErr: false,
+		},
+		// \w 패턴 테스트
+		{
+			name:    "\\w matches word char",
+			line:    []byte("hello"),
+			pattern: "\\w",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\w no match",
+			line:    []byte("!!!"),
+			pattern: "\\w",
+			want:    false,
+			wantErr: false,
+		},
+		// 단일 문자 테스트
+		{
+			name:    "single char match",
+			line:    []byte("apple"),
+			pattern: "a",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "single char no match",
+			line:    []byte("apple"),
+			pattern: "z",
+			want:    false,
+			wantErr: false,
+		},
+		// Positive character groups 테스트
+		{
+			name:    "[abc] matches a",
+			line:    []byte("apple"),
+			pattern: "[abc]",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[abc] matches b",
+			line:    []byte("banana"),
+			pattern: "[abc]",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[abc] no match",
+			line:    []byte("xyz"),
+			pattern: "[abc]",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "[xyz] matches z",
+			line:    []byte("buzz"),
+			pattern: "[xyz]",
+			want:    true,
+			wantErr: false,
+		},
+		// Negative character groups 테스트
+		{
+			name:    "[^abc] matches cat (has t)",
+			line:    []byte("cat"),
+			pattern: "[^abc]",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[^abc] does not match cab",
+			line:    []byte("cab"),
+			pattern: "[^abc]",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "[^ab] matches abc (has c)",
+			line:    []byte("abc"),
+			pattern: "[^ab]",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[^xyz] matches hello",
+			line:    []byte("hello"),
+			pattern: "[^xyz]",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[^xyz] does not match xyz",
+			line:    []byte("xyz"),
+			pattern: "[^xyz]",
+			want:    false,
+			wantErr: false,
+		},
+		// Pattern sequence tests
+		{
+			name:    "\\d apple matches 1 apple",
+			line:    []byte("1 apple"),
+			pattern: "\\d apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\d apple does not match 1 orange",
+			line:    []byte("1 orange"),
+			pattern: "\\d apple",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "\\d\\d\\d apple matches 100 apples",
+			line:    []byte("100 apples"),
+			pattern: "\\d\\d\\d apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\d\\d\\d apple does not match 1 apple",
+			line:    []byte("1 apple"),
+			pattern: "\\d\\d\\d apple",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "\\d \\w\\w\\ws matches 3 dogs",
+			line:    []byte("3 dogs"),
+			pattern: "\\d \\w\\w\\ws",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\d \\w\\w\\ws matches 4 cats",
+			line:    []byte("4 cats"),
+			pattern: "\\d \\w\\w\\ws",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\d \\w\\w\\ws does not match 1 dog",
+			line:    []byte("1 dog"),
+			pattern: "\\d \\w\\w\\ws",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "multi-char sequence abc",
+			line:    []byte("xyzabcdef"),
+			pattern: "abc",
+			want:    true,
+			wantErr: false,
+		},
+		// Start of string anchor tests
+		{
+			name:    "^apple matches apple pie",
+			line:    []byte("apple pie"),
+			pattern: "^apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "^apple does not match green apple",
+			line:    []byte("green apple"),
+			pattern: "^apple",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "^apple matches apple exactly",
+			line:    []byte("apple"),
+			pattern: "^apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "^log matches log message",
+			line:    []byte("log message"),
+			pattern: "^log",
+			want:    true,
+			wantErr: false,
+		},
+		// End of string anchor tests
+		{
+			name:    "apple$ matches green apple",
+			line:    []byte("green apple"),
+			pattern: "apple$",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "apple$ does not match apple pie",
+			line:    []byte("apple pie"),
+			pattern: "apple$",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "apple$ matches apple exactly",
+			line:    []byte("apple"),
+			pattern: "apple$",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "world$ matches hello world",
+			line:    []byte("hello world"),
+			pattern: "world$",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "strawberry$ matches orange_strawberry",
+			line:    []byte("orange_strawberry"),
+			pattern: "strawberry$",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "strawberry$ does not match strawberry_orange",
+			line:    []byte("strawberry_orange"),
+			pattern: "strawberry$",
+			want:    false,
+			wantErr: false,
+		},
+		// Both anchors: ^...$ (exact match)
+		{
+			name:    "^apple$ matches apple exactly",
+			line:    []byte("apple"),
+			pattern: "^apple$",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "^apple$ does not match apple pie",
+			line:    []byte("apple pie"),
+			pattern: "^apple$",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "^apple$ does not match green apple",
+			line:    []byte("green apple"),
+			pattern: "^apple$",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "^cat$ matches cat exactly",
+			line:    []byte("cat"),
+			pattern: "^cat$",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "^cat$ does not match cats",
+			line:    []byte("cats"),
+			pattern: "^cat$",
+			want:    false,
+			wantErr: false,
+		},
+		// + quantifier tests (one or more)
+		{
+			name:    "ca+ts matches cats (1 a)",
+			line:    []byte("cats"),
+			pattern: "ca+ts",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "ca+ts matches caats (2 a's)",
+			line:    []byte("caats"),
+			pattern: "ca+ts",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "ca+ts matches caaats (3 a's)",
+			line:    []byte("caaats"),
+			pattern: "ca+ts",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "ca+ts does not match cts (0 a's)",
+			line:    []byte("cts"),
+			pattern: "ca+ts",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "a+ matches a",
+			line:    []byte("a"),
+			pattern: "a+",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a+ matches aaa",
+			line:    []byte("aaa"),
+			pattern: "a+",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a+ does not match b",
+			line:    []byte("b"),
+			pattern: "a+",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "a+b matches ab",
+			line:    []byte("ab"),
+			pattern: "a+b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a+b matches aaab",
+			line:    []byte("aaab"),
+			pattern: "a+b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a+b does not match b",
+			line:    []byte("b"),
+			pattern: "a+b",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "a+b+ matches ab",
+			line:    []byte("ab"),
+			pattern: "a+b+",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a+b+ matches aaabbb",
+			line:    []byte("aaabbb"),
+			pattern: "a+b+",
+			want:    true,
+			wantErr: false,
+		},
+		// Backtracking tests (cases where greedy matching would fail)
+		{
+			name:    "ca+at matches caaats (backtracking needed)",
+			line:    []byte("caaats"),
+			pattern: "ca+at",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "ca+at matches caats (no backtracking needed)",
+			line:    []byte("caats"),
+			pattern: "ca+at",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "ca+at does not match cts",
+			line:    []byte("cts"),
+			pattern: "ca+at",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "ca+at does not match caaaa (no 't' at end)",
+			line:    []byte("caaaa"),
+			pattern: "ca+at",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "a+b matches aaab (backtracking needed)",
+			line:    []byte("aaab"),
+			pattern: "a+b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\d+apple matches 123apple (backtracking might be needed)",
+			line:    []byte("123apple"),
+			pattern: "\\d+apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\d+ apple matches 100 apples (backtracking for space)",
+			line:    []byte("100 apples"),
+			pattern: "\\d+ apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a+b+c matches aaabbbc (multiple quantifiers with backtracking)",
+			line:    []byte("aaabbbc"),
+			pattern: "a+b+c",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a+b+c matches abc (minimal match)",
+			line:    []byte("abc"),
+			pattern: "a+b+c",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[abc]+d matches abcd (backtracking with char class)",
+			line:    []byte("abcd"),
+			pattern: "[abc]+d",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[abc]+d matches aaabbbcccd (backtracking with char class)",
+			line:    []byte("aaabbbcccd"),
+			pattern: "[abc]+d",
+			want:    true,
+			wantErr: false,
+		},
+		// Literal backslash tests
+		{
+			name:    "\\\\ matches backslash character",
+			line:    []byte("a\\b"),
+			pattern: "a\\\\b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\\\ does not match when no backslash present",
+			line:    []byte("ab"),
+			pattern: "a\\\\b",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "\\\\d matches backslash followed by 'd'",
+			line:    []byte("test\\d123"),
+			pattern: "\\\\d",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\\\d does not match digit (should match literal \\d)",
+			line:    []byte("test5"),
+			pattern: "\\\\d",
+			want:    false,
+			wantErr: false,
+		},
+		// Zero or One (?) quantifier tests
+		{
+			name:    "dogs? matches dog (0 s)",
+			line:    []byte("dog"),
+			pattern: "dogs?",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "dogs? matches dogs (1 s)",
+			line:    []byte("dogs"),
+			pattern: "dogs?",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "colou?r matches color (0 u)",
+			line:    []byte("color"),
+			pattern: "colou?r",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "colou?r matches colour (1 u)",
+			line:    []byte("colour"),
+			pattern: "colou?r",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a? matches empty at start",
+			line:    []byte("b"),
+			pattern: "a?b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a? matches single a",
+			line:    []byte("ab"),
+			pattern: "a?b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\\\d? matches 0 digits",
+			line:    []byte("apple"),
+			pattern: "\\d?apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "\\\\d? matches 1 digit",
+			line:    []byte("1apple"),
+			pattern: "\\d?apple",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[abc]? matches 0 chars",
+			line:    []byte("dog"),
+			pattern: "[abc]?dog",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "[abc]? matches 1 char",
+			line:    []byte("adog"),
+			pattern: "[abc]?dog",
+			want:    true,
+			wantErr: false,
+		},
+		// Dot (.) metacharacter tests
+		{
+			name:    "d.g matches dog",
+			line:    []byte("dog"),
+			pattern: "d.g",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "d.g matches dag",
+			line:    []byte("dag"),
+			pattern: "d.g",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "d.g matches d1g",
+			line:    []byte("d1g"),
+			pattern: "d.g",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "d.g matches d@g",
+			line:    []byte("d@g"),
+			pattern: "d.g",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "d.g does not match cog (first char mismatch)",
+			line:    []byte("cog"),
+			pattern: "d.g",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "d.g does not match dg (missing middle char)",
+			line:    []byte("dg"),
+			pattern: "d.g",
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "... matches any 3 chars",
+			line:    []byte("abc"),
+			pattern: "...",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "... matches 123",
+			line:    []byte("123"),
+			pattern: "...",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    ".+ matches one or more chars",
+			line:    []byte("hello"),
+			pattern: ".+",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a.?b matches ab (0 chars)",
+			line:    []byte("ab"),
+			pattern: "a.?b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "a.?b matches acb (1 char)",
+			line:    []byte("acb"),
+			pattern: "a.?b",
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "c.t matches cat",
+			line:    []byte("cat"),
+			pattern: "c.t",
+			want:    true,
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchLine(tt.line, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("matchLine() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("matchLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    []byte
+		pattern string
+		want    []int
+		wantErr bool
+	}{
+		{
+			name:    "literal in the middle",
+			line:    []byte("xyzabcdef"),
+			pattern: "abc",
+			want:    []int{3, 6},
+		},
+		{
+			name:    "no match",
+			line:    []byte("hello"),
+			pattern: "\\d",
+			want:    nil,
+		},
+		{
+			name:    "leftmost match wins",
+			line:    []byte("cat cats"),
+			pattern: "cats?",
+			want:    []int{0, 3},
+		},
+		{
+			name:    "+ is greedy",
+			line:    []byte("the caaats sat"),
+			pattern: "ca+",
+			want:    []int{4, 8},
+		},
+		{
+			name:    "+ gives back for the rest of the pattern",
+			line:    []byte("caaats"),
+			pattern: "ca+at",
+			want:    []int{0, 5},
+		},
+		{
+			name:    "? is greedy",
+			line:    []byte("colour"),
+			pattern: "colou?",
+			want:    []int{0, 5},
+		},
+		{
+			name:    "\\d+ takes every digit",
+			line:    []byte("order 12345 shipped"),
+			pattern: "\\d+",
+			want:    []int{6, 11},
+		},
+		{
+			name:    "start anchor",
+			line:    []byte("log: log"),
+			pattern: "^log",
+			want:    []int{0, 3},
+		},
+		{
+			name:    "end anchor picks the last occurrence",
+			line:    []byte("log: log"),
+			pattern: "log$",
+			want:    []int{5, 8},
+		},
+		{
+			name:    "both anchors",
+			line:    []byte("123-abc"),
+			pattern: "^\\d+-\\w+$",
+			want:    []int{0, 7},
+		},
+		{
+			name:    "empty match at the start",
+			line:    []byte("bbb"),
+			pattern: "a?",
+			want:    []int{0, 0},
+		},
+		{
+			name:    "empty match on empty line",
+			line:    []byte(""),
+			pattern: "^$",
+			want:    []int{0, 0},
+		},
+		{
+			name:    "dot does not cross a newline",
+			line:    []byte("d\ng dog"),
+			pattern: "d.g",
+			want:    []int{4, 7},
+		},
+		{
+			name:    "invalid pattern",
+			line:    []byte("abc"),
+			pattern: "[abc",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findLine(tt.line, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("findLine() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindAllLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    []byte
+		pattern string
+		want    [][]int
+		wantErr bool
+	}{
+		{
+			name:    "every occurrence",
+			line:    []byte("cat dog cat"),
+			pattern: "cat",
+			want:    [][]int{{0, 3}, {8, 11}},
+		},
+		{
+			name:    "matches do not overlap",
+			line:    []byte("aaaa"),
+			pattern: "aa",
+			want:    [][]int{{0, 2}, {2, 4}},
+		},
+		{
+			name:    "no match",
+			line:    []byte("hello"),
+			pattern: "\\d",
+			want:    nil,
+		},
+		{
+			name:    "empty matches between characters",
+			line:    []byte("ab"),
+			pattern: "x?",
+			want:    [][]int{{0, 0}, {1, 1}, {2, 2}},
+		},
+		{
+			name:    "no empty match right after a match",
+			line:    []byte("ab"),
+			pattern: "a?",
+			want:    [][]int{{0, 1}, {2, 2}},
+		},
+		{
+			name:    "empty matches advance by rune",
+			line:    []byte("é"),
+			pattern: "x?",
+			want:    [][]int{{0, 0}, {2, 2}},
+		},
+		{
+			name:    "dot matches a whole character",
+			line:    []byte("aé日"),
+			pattern: ".",
+			want:    [][]int{{0, 1}, {1, 3}, {3, 6}},
+		},
+		{
+			name:    "start anchor matches once",
+			line:    []byte("aaa"),
+			pattern: "^a",
+			want:    [][]int{{0, 1}},
+		},
+		{
+			name:    "invalid pattern",
+			line:    []byte("abc"),
+			pattern: "+",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findAllLine(tt.line, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("findAllLine() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findAllLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// matchLine compiles the pattern and reports whether it matches the line.
+func matchLine(line []byte, pattern string) (bool, error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.Match(line)
+}
+
+// findLine compiles the pattern and returns its leftmost match in the line.
+func findLine(line []byte, pattern string) ([]int, error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.FindSubmatchIndex(line)
+}
+
+// findAllLine compiles the pattern and returns all of its matches in the line.
+func findAllLine(line []byte, pattern string) ([][]int, error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.FindAllIndex(line, -1)
+}
+
+func TestRegexpFind(t *testing.T) {
+	re := MustCompile("\\d+")
+	line := []byte("order 12, 345 and 6")
+
+	if got, err := re.Find(line); err != nil || string(got) != "12" {
+		t.Errorf("Find() = %q, %v, want \"12\"", got, err)
+	}
+	if got, err := re.FindIndex(line); err != nil || !reflect.DeepEqual(got, []int{6, 8}) {
+		t.Errorf("FindIndex() = %v, %v, want [6 8]", got, err)
+	}
+	if got, err := re.FindSubmatch(line); err != nil || len(got) != 1 || string(got[0]) != "12" {
+		t.Errorf("FindSubmatch() = %q, %v, want [\"12\"]", got, err)
+	}
+
+	all, err := re.FindAll(line, -1)
+	if err != nil {
+		t.Fatalf("FindAll() error = %v", err)
+	}
+	var got []string
+	for _, m := range all {
+		got = append(got, string(m))
+	}
+	if want := []string{"12", "345", "6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAll() = %q, want %q", got, want)
+	}
+
+	if got, err := re.Find([]byte("none")); err != nil || got != nil {
+		t.Errorf("Find() without a match = %q, %v, want nil", got, err)
+	}
+	if got, err := re.FindAll([]byte("none"), -1); err != nil || got != nil {
+		t.Errorf("FindAll() without a match = %q, %v, want nil", got, err)
+	}
+}
+
+func TestMustCompile(t *testing.T) {
+	if re := MustCompile("a+b"); re.String() != "a+b" {
+		t.Errorf("String() = %q, want %q", re.String(), "a+b")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustCompile() of an invalid pattern did not panic")
+		}
+	}()
+	MustCompile("[abc")
+}
+
+func TestRegexpConcurrentUse(t *testing.T) {
+	// Patterns that run on the lazy DFA, the reverse DFA and the backtracker
+	patterns := []string{
+		strings.Repeat("\\w", 70) + "x?",
+		"\\d+ apples$",
+		"\\w+ing",
+	}
+	lines := []string{
+		strings.Repeat("a", 80),
+		"I have 12 apples",
+		"nothing to see",
+		"12 apples and pears",
+	}
+
+	for _, pattern := range patterns {
+		re := MustCompile(pattern)
+		var want []bool
+		for _, line := range lines {
+			want = append(want, mustMatch(t)(re.Match([]byte(line))))
+		}
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					for j, line := range lines {
+						got, err := re.Match([]byte(line))
+						if err != nil || got != want[j] {
+							t.Errorf("Match(%q, %q) = %v, %v, want %v", pattern, line, got, err, want[j])
+							return
+						}
+						if _, err := re.FindIndex([]byte(line)); err != nil {
+							t.Errorf("FindIndex() error = %v", err)
+							return
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestRegexpContext(t *testing.T) {
+	re := MustCompile("\\w+\\d+x")
+	input := []byte(strings.Repeat("ab1", 300) + "x")
+
+	ctx := context.Background()
+	if ok, err := re.MatchContext(ctx, input); !ok || err != nil {
+		t.Errorf("MatchContext() = %v, %v; want true, nil", ok, err)
+	}
+	if loc, err := re.FindIndexContext(ctx, input); loc == nil || err != nil {
+		t.Errorf("FindIndexContext() = %v, %v; want a match", loc, err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	searches := map[string]func() error{
+		"MatchContext": func() error {
+			_, err := re.MatchContext(cancelled, input)
+			return err
+		},
+		"FindIndexContext": func() error {
+			_, err := re.FindIndexContext(cancelled, input)
+			return err
+		},
+		"FindAllIndexContext": func() error {
+			_, err := re.FindAllIndexContext(cancelled, input, -1)
+			return err
+		},
+		"ForEachMatchContext": func() error {
+			return re.ForEachMatchContext(cancelled, input, func([]int) bool { return true })
+		},
+		"ScanContext": func() error {
+			return re.ScanContext(cancelled, strings.NewReader("a1x\nb2x\n"), func(Line) bool { return true })
+		},
+	}
+	for name, search := range searches {
+		if err := search(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s() error = %v, want %v", name, err, context.Canceled)
+		}
+	}
+}
+
+func TestRegexpContextDeadline(t *testing.T) {
+	re := MustCompile("\\d+x")
+	re.SetBacktrackLimit(0)
+	input := []byte(strings.Repeat("1", 1<<20))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := re.MatchContext(ctx, input); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("MatchContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestForEachMatchStepLimitPerMatch(t *testing.T) {
+	// Each match takes a few steps; together they take far more than the
+	// limit, which bounds a single search
+	re := MustCompile("a\\w?")
+	re.SetBacktrackLimit(1000)
+	input := []byte(strings.Repeat("ab ", 10_000))
+
+	n := 0
+	err := re.ForEachMatch(input, func(loc []int) bool {
+		n++
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ForEachMatch() error = %v", err)
+	}
+	if n != 10_000 {
+		t.Errorf("ForEachMatch() found %d matches, want %d", n, 10_000)
+	}
+}
+
+func TestFindLongFailedRun(t *testing.T) {
+	// Every a of the run could start a match that fails at the z; only the
+	// last three bytes match. Trying each start in turn takes quadratic time.
+	re := MustCompile("a+c")
+	input := []byte(strings.Repeat("a", 30_000) + "zac")
+
+	start := time.Now()
+	var got [][]int
+	err := re.ForEachMatch(input, func(loc []int) bool {
+		got = append(got, loc)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ForEachMatch() error = %v", err)
+	}
+	if want := [][]int{{30_001, 30_003}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ForEachMatch() = %v, want %v", got, want)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ForEachMatch() took %v", elapsed)
+	}
+}
+
+func TestFinder(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		pos     int
+		want    []int
+	}{
+		{"a\\d", "a1 a2 a3", 0, []int{0, 2}},
+		{"a\\d", "a1 a2 a3", 1, []int{3, 5}},
+		{"a\\d", "a1 a2 a3", 6, []int{6, 8}},
+		{"a\\d", "a1 a2 a3", 7, nil},
+		{"^a", "aaa", 0, []int{0, 1}},
+		{"^a", "aaa", 1, nil}, // ^ only matches at the start of the input
+		{"a$", "aaa", 0, []int{2, 3}},
+		{"b?", "abc", 2, []int{2, 2}},
+		{"x", "abc", 3, nil},
+	}
+
+	for _, tt := range tests {
+		f := MustCompile(tt.pattern).NewFinder([]byte(tt.input))
+		got, err := f.FindIndexAt(tt.pos)
+		if err != nil {
+			t.Fatalf("FindIndexAt() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q in %q from %d = %v, want %v", tt.pattern, tt.input, tt.pos, got, tt.want)
+		}
+	}
+
+	// Searching the same input again reuses the memo of the first search
+	f := MustCompile("\\w+!").NewFinder([]byte("ab! cd! ef"))
+	for _, tt := range []struct {
+		pos  int
+		want []int
+	}{{0, []int{0, 3}}, {3, []int{4, 7}}, {7, nil}} {
+		got, err := f.FindIndexAt(tt.pos)
+		if err != nil {
+			t.Fatalf("FindIndexAt(%d) error = %v", tt.pos, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindIndexAt(%d) = %v, want %v", tt.pos, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package regex
+
+import (
+	"bytes"
+	"errors"
+)
+
+// minReverseSuffix is the shortest literal suffix worth searching for. Shorter
+// suffixes occur so often that scanning back from each of them costs more than
+// a forward scan of the line.
+const minReverseSuffix = 2
+
+// reverseWorkFactor bounds the bytes the suffix scans may read backwards, as a
+// multiple of the length of the line. Each occurrence of the suffix starts a
+// scan of its own, and a scan can run back over what earlier ones read, so
+// without a bound a line full of occurrences takes quadratic time.
+const reverseWorkFactor = 4
+
+// errReverseWork is returned by the reverse searcher when it has read more than
+// its share of bytes. The caller then matches the line with a forward engine,
+// which takes linear time.
+var errReverseWork = errors.New("regex: reverse scan gave up")
+
+// reverseSearcher matches patterns from the end instead of from every start
+// position. It runs a lazy DFA built from the reversed tokens, anchored at the
+// offset where the backwards scan begins:
+//
+//   - for patterns ending with $, at the end of the line, so a line that can't
+//     match is usually rejected after looking at its last few bytes;
+//   - for patterns ending with a literal, at the end of each occurrence of that
+//     literal, which bytes.Index finds far faster than any engine could.
+type reverseSearcher struct {
+	dfas        *dfaPool
+	suffix      []byte // literal every match ends with; nil when scanning from the end of the line
+	anchoredEnd bool
+}
+
+// newReverseSearcher returns a reverse searcher for the pattern, or nil if
+// matching it backwards isn't expected to pay off. prefix is the pattern's
+// literal prefix; patterns that have one are already fast to scan forwards.
+func newReverseSearcher(tokens []token, anchoredStart, anchoredEnd bool, prefix []byte) *reverseSearcher {
+	r := &reverseSearcher{anchoredEnd: anchoredEnd}
+
+	if !anchoredEnd {
+		r.suffix = literalSuffix(tokens)
+		if len(r.suffix) < minReverseSuffix || prefix != nil {
+			return nil
+		}
+	}
+
+	// The reversed automaton starts where the original ends: it is anchored at
+	// the offset the scan begins from, and at offset 0 if the pattern has ^
+	m := newNFA(reverseTokens(tokens), true, anchoredStart)
+	r.dfas = newDFAPool(m)
+	return r
+}
+
+// literalSuffix returns the run of unquantified literal tokens the pattern ends with.
+func literalSuffix(tokens []token) []byte {
+	i := len(tokens)
+	for i > 0 && tokens[i-1].Type == literal && tokens[i-1].Quantifier == none {
+		i--
+	}
+	var suffix []byte
+	for _, tok := range tokens[i:] {
+		suffix = append(suffix, tok.Value[0])
+	}
+	return suffix
+}
+
+// reverseTokens returns the tokens in reverse order, with the byte sequences of
+// multibyte tokens reversed as well, so the reversed tokens match exactly the
+// reversed matches.
+func reverseTokens(tokens []token) []token {
+	reversed := make([]token, len(tokens))
+	for i, tok := range tokens {
+		if tok.multibyte() {
+			tok.set = tok.set.reversed()
+		}
+		reversed[len(tokens)-1-i] = tok
+	}
+	return reversed
+}
+
+// match reports whether the pattern matches anywhere in inputText. It returns
+// errReverseWork if the scans back from the occurrences of the suffix read more
+// than reverseWorkFactor times the length of inputText.
+func (r *reverseSearcher) match(inputText []byte, b *budget) (bool, error) {
+	d := r.dfas.get()
+	defer r.dfas.put(d)
+
+	// A single scan from the end of the line reads it at most once
+	work := len(inputText) + 1
+	if r.suffix == nil {
+		return d.matchReverse(inputText, len(inputText), &work, b)
+	}
+
+	work = reverseWorkFactor*len(inputText) + checkInterval
+	for i := 0; ; {
+		j := bytes.Index(inputText[i:], r.suffix)
+		if j < 0 {
+			return false, nil
+		}
+		ok, err := d.matchReverse(inputText, i+j+len(r.suffix), &work, b)
+		if ok || err != nil {
+			return ok, err
+		}
+		i += j + 1
+	}
+}
+
+// matchReverse reports whether the automaton accepts the bytes of inputText read
+// backwards from end. Every byte read is taken from work, and the scan stops
+// with errReverseWork once none is left.
+func (d *dfa) matchReverse(inputText []byte, end int, work *int, b *budget) (bool, error) {
+	init := newStateSet(d.nfa.numStates())
+	d.nfa.start(init)
+	s, ok := d.lookup(init)
+	if !ok {
+		return d.nfa.matchReverseFrom(inputText, end, init, work, b)
+	}
+
+	for i := end - 1; ; i-- {
+		state := d.states[s]
+		if !d.nfa.anchoredEnd && state.accept {
+			return true, nil
+		}
+		if i < 0 {
+			return state.accept, nil
+		}
+		if state.set.empty() {
+			return false, nil // dead state: no match can start or continue
+		}
+		if err := b.poll(end - i); err != nil {
+			return false, err
+		}
+		if *work--; *work < 0 {
+			return false, errReverseWork
+		}
+
+		next := d.transition(s, inputText[i])
+		if next == dfaFull {
+			// Out of cache: finish the search by simulating the NFA from here
+			*work++ // the byte is read again by the NFA
+			return d.nfa.matchReverseFrom(inputText, i+1, append(stateSet(nil), state.set...), work, b)
+		}
+		s = next
+	}
+}
+
+// matchReverseFrom continues a backwards simulation whose current states are cur
+// with the bytes before offset end still to be read.
+func (m *nfa) matchReverseFrom(inputText []byte, end int, cur stateSet, work *int, b *budget) (bool, error) {
+	next := newStateSet(m.numStates())
+	for i := end - 1; ; i-- {
+		if !m.anchoredEnd && m.accepts(cur) {
+			return true, nil
+		}
+		if i < 0 {
+			break
+		}
+		if cur.empty() {
+			return false, nil
+		}
+		if err := b.poll(end - i); err != nil {
+			return false, err
+		}
+		if *work--; *work < 0 {
+			return false, errReverseWork
+		}
+		m.step(cur, inputText[i], next)
+		cur, next = next, cur
+	}
+	return m.accepts(cur), nil
+}
@@ -0,0 +1,116 @@
+package regex
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReverseSearcher(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		want       bool
+		wantSuffix string
+	}{
+		{pattern: "error: \\w+$", want: true},
+		{pattern: "\\d+ apples", want: true, wantSuffix: " apples"},
+		{pattern: "\\w+ing", want: true, wantSuffix: "ing"},
+		{pattern: "\\w+s", want: false},      // suffix too short
+		{pattern: "apple\\w+s", want: false}, // literal prefix scans forwards
+		{pattern: "\\d+", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePattern() error = %v", err)
+			}
+			if got := p.reverse != nil; got != tt.want {
+				t.Fatalf("reverse = %v, want %v", got, tt.want)
+			}
+			if p.reverse != nil && string(p.reverse.suffix) != tt.wantSuffix {
+				t.Errorf("suffix = %q, want %q", p.reverse.suffix, tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestReverseSearcherMatch(t *testing.T) {
+	cases := append(engineTests[:len(engineTests):len(engineTests)], []struct {
+		pattern string
+		inputs  []string
+	}{
+		{"error: \\w+$", []string{"fatal error: disk", "error: ", "error: disk full", "xerror: a"}},
+		{"\\d+ apples", []string{"100 apples", " apples 1 apples", "x apples", "1 apple"}},
+		{"\\w?ing", []string{"ing", "xing", "thing", "in g"}},
+		{"a.?b.?cd", []string{"abcd", "axbxcd", "abcdcd", "acd"}},
+	}...)
+
+	for _, tt := range cases {
+		tokens, anchoredStart, anchoredEnd := compileForTest(t, tt.pattern)
+		suffix := literalSuffix(tokens)
+		if !anchoredEnd && len(suffix) == 0 {
+			continue // no place to scan backwards from
+		}
+		r := newReverseSearcher(tokens, anchoredStart, anchoredEnd, nil)
+		if r == nil {
+			// Suffix too short to be chosen; exercise it anyway
+			r = &reverseSearcher{
+				dfas:   newDFAPool(newNFA(reverseTokens(tokens), true, anchoredStart)),
+				suffix: suffix,
+			}
+		}
+		for _, input := range tt.inputs {
+			want := referenceMatch(t, tt.pattern, input)
+			if got := mustMatch(t)(r.match([]byte(input), unlimited())); got != want {
+				t.Errorf("reverse.match(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
+
+func TestReverseSearcherManySuffixes(t *testing.T) {
+	// Every occurrence of the suffix starts a scan back to the start of the
+	// line, which would take quadratic time without a bound on the work
+	p, err := compilePattern("^\\w+xab")
+	if err != nil {
+		t.Fatalf("compilePattern() error = %v", err)
+	}
+	if p.reverse == nil {
+		t.Fatal("pattern isn't matched backwards")
+	}
+	line := []byte("!" + strings.Repeat("xab", 1_000_000))
+
+	if _, err := p.reverse.match(line, unlimited()); err != errReverseWork {
+		t.Fatalf("reverse.match() error = %v, want errReverseWork", err)
+	}
+	start := time.Now()
+	if got := mustMatch(t)(p.match(context.Background(), line)); got {
+		t.Errorf("match() = true, want false")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("match() took %v on a %d-byte line", elapsed, len(line))
+	}
+
+	line[0] = 'w'
+	if got := mustMatch(t)(p.match(context.Background(), line)); !got {
+		t.Errorf("match() = false, want true")
+	}
+}
+
+func TestReverseTokens(t *testing.T) {
+	tokens, err := parseTokens("ab+\\d?")
+	if err != nil {
+		t.Fatalf("parseTokens() error = %v", err)
+	}
+	want := []token{
+		{Type: digit, Value: "\\d", Quantifier: zeroOrOne},
+		{Type: literal, Value: "b", Quantifier: oneOrMore},
+		{Type: literal, Value: "a", Quantifier: none},
+	}
+	if got := reverseTokens(tokens); !equalTokenSlices(got, want) {
+		t.Errorf("reverseTokens() = %+v, want %+v", got, want)
+	}
+}
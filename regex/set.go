@@ -0,0 +1,299 @@
+package regex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RegexSet is a set of patterns that are matched against the input together,
+// in a single pass, to find out which of them match.
+//
+// The position automata of all patterns are merged into one automaton whose
+// accepting states remember the pattern they belong to. Running it over the
+// input once, through a lazy DFA, finds every pattern that matches, however
+// many patterns there are.
+//
+// A RegexSet is safe for concurrent use by multiple goroutines, except for
+// SetDeadline, which must be called before the RegexSet is shared.
+type RegexSet struct {
+	exprs  []string
+	m      *setNFA
+	dfas   sync.Pool // of *setDFA
+	limits matchLimits
+}
+
+// CompileSet parses the patterns and returns a RegexSet that matches them all
+// at once. Pattern indices in the results refer to positions in exprs.
+func CompileSet(exprs []string) (*RegexSet, error) {
+	patterns := make([]setPattern, len(exprs))
+	for i, expr := range exprs {
+		tokens, anchoredStart, anchoredEnd, err := parsePattern(expr)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", i, err)
+		}
+		patterns[i] = setPattern{tokens: tokens, anchoredStart: anchoredStart, anchoredEnd: anchoredEnd}
+	}
+
+	s := &RegexSet{exprs: append([]string(nil), exprs...), m: newSetNFA(patterns)}
+	proto := newSetDFA(s.m, defaultDFACacheSize)
+	s.dfas.New = func() any {
+		d := *proto
+		d.index = make(map[string]int)
+		return &d
+	}
+	return s, nil
+}
+
+// MustCompileSet is like CompileSet but panics if a pattern cannot be parsed.
+func MustCompileSet(exprs []string) *RegexSet {
+	s, err := CompileSet(exprs)
+	if err != nil {
+		panic(`regex: CompileSet: ` + err.Error())
+	}
+	return s
+}
+
+// Len returns the number of patterns in the set.
+func (s *RegexSet) Len() int {
+	return len(s.exprs)
+}
+
+// Patterns returns the source text of the patterns in the set.
+func (s *RegexSet) Patterns() []string {
+	return append([]string(nil), s.exprs...)
+}
+
+// SetDeadline makes searches still running at t fail with ErrTimeout. The zero
+// time means no deadline, which is the default.
+func (s *RegexSet) SetDeadline(t time.Time) {
+	s.limits.deadline = t
+}
+
+// Match reports whether any pattern in the set matches b.
+func (s *RegexSet) Match(b []byte) (bool, error) {
+	return s.MatchContext(context.Background(), b)
+}
+
+// MatchContext is like Match but gives up with ctx.Err() once ctx is done.
+func (s *RegexSet) MatchContext(ctx context.Context, b []byte) (bool, error) {
+	found, err := s.run(ctx, b, true)
+	return len(found) > 0, err
+}
+
+// Matches returns the indices, in increasing order, of the patterns that match
+// b. It returns nil if none of them do.
+func (s *RegexSet) Matches(b []byte) ([]int, error) {
+	return s.MatchesContext(context.Background(), b)
+}
+
+// MatchesContext is like Matches but gives up with ctx.Err() once ctx is done.
+func (s *RegexSet) MatchesContext(ctx context.Context, b []byte) ([]int, error) {
+	return s.run(ctx, b, false)
+}
+
+// run searches b and returns the patterns that match, stopping at the first one
+// if first is set.
+func (s *RegexSet) run(ctx context.Context, b []byte, first bool) ([]int, error) {
+	budget := newBudget(ctx, s.limits)
+	if err := budget.expired(); err != nil {
+		return nil, err
+	}
+	d := s.dfas.Get().(*setDFA)
+	defer s.dfas.Put(d)
+
+	found := newSetMatches(len(s.exprs), first)
+	if err := d.match(b, found, budget); err != nil {
+		return nil, err
+	}
+	return found.indices(), nil
+}
+
+// setPattern is a parsed pattern of a RegexSet.
+type setPattern struct {
+	tokens        []token
+	anchoredStart bool
+	anchoredEnd   bool
+}
+
+// setNFA is the union of the position automata of several patterns.
+//
+// Each pattern keeps its own states, numbered from an offset into the merged
+// automaton: its start state, then its positions. As in nfa, entering a
+// position consumes one byte of its set.
+type setNFA struct {
+	sets    []*byteSet // sets[s] holds the bytes consumed when entering s; nil for start states
+	follow  [][]int    // follow[s] lists the states reachable from s by consuming one byte
+	owner   []int      // owner[s] is the pattern state s belongs to
+	accept  []bool     // accept[s] reports whether owner[s] has matched in state s
+	initial []int      // start states at offset 0: those of every pattern
+	restart []int      // start states at later offsets: those of unanchored patterns
+
+	anchoredEnd []bool // anchoredEnd[i] reports whether pattern i ended with $
+}
+
+// newSetNFA merges the position automata of the patterns.
+func newSetNFA(patterns []setPattern) *setNFA {
+	m := &setNFA{anchoredEnd: make([]bool, len(patterns))}
+	for i, p := range patterns {
+		sub := newNFA(p.tokens, p.anchoredStart, p.anchoredEnd)
+		offset := len(m.accept)
+
+		for s := 0; s < sub.numStates(); s++ {
+			follow := make([]int, len(sub.follow[s]))
+			for j, t := range sub.follow[s] {
+				follow[j] = offset + t
+			}
+			m.sets = append(m.sets, sub.sets[s])
+			m.follow = append(m.follow, follow)
+			m.owner = append(m.owner, i)
+			m.accept = append(m.accept, sub.accept[s])
+		}
+
+		m.initial = append(m.initial, offset)
+		if !p.anchoredStart {
+			m.restart = append(m.restart, offset)
+		}
+		m.anchoredEnd[i] = p.anchoredEnd
+	}
+	return m
+}
+
+// numStates returns the number of automaton states.
+func (m *setNFA) numStates() int {
+	return len(m.accept)
+}
+
+// start fills set with the states active at offset 0.
+func (m *setNFA) start(set stateSet) {
+	set.clear()
+	for _, s := range m.initial {
+		set.add(s)
+	}
+}
+
+// step advances every state in cur over byte b and stores the result in next,
+// then adds the start states of the patterns not anchored at the start.
+func (m *setNFA) step(cur stateSet, b byte, next stateSet) {
+	next.clear()
+	for s := 0; s < m.numStates(); s++ {
+		if !cur.has(s) {
+			continue
+		}
+		for _, t := range m.follow[s] {
+			if !next.has(t) && m.sets[t].has(b) {
+				next.add(t)
+			}
+		}
+	}
+	for _, s := range m.restart {
+		next.add(s)
+	}
+}
+
+// accepting returns the patterns that have matched in the given set of states.
+func (m *setNFA) accepting(set stateSet) []int {
+	var patterns []int
+	for s, ok := range m.accept {
+		if ok && set.has(s) {
+			patterns = append(patterns, m.owner[s])
+		}
+	}
+	return patterns
+}
+
+// setMatches records the patterns found by a search.
+type setMatches struct {
+	found []bool
+	count int
+	first bool // stop at the first pattern found
+}
+
+func newSetMatches(n int, first bool) *setMatches {
+	return &setMatches{found: make([]bool, n), first: first}
+}
+
+// add records the accepting patterns of a state reached at offset i of an input
+// of length n. Patterns ending with $ only count at the end of the input. It
+// reports whether the search is over.
+func (f *setMatches) add(m *setNFA, patterns []int, i, n int) bool {
+	for _, p := range patterns {
+		if !f.found[p] && (!m.anchoredEnd[p] || i == n) {
+			f.found[p] = true
+			f.count++
+		}
+	}
+	return f.count == len(f.found) || (f.first && f.count > 0)
+}
+
+// indices returns the patterns found, in increasing order, or nil.
+func (f *setMatches) indices() []int {
+	if f.count == 0 {
+		return nil
+	}
+	indices := make([]int, 0, f.count)
+	for p, ok := range f.found {
+		if ok {
+			indices = append(indices, p)
+		}
+	}
+	return indices
+}
+
+// matchFrom continues a simulation whose current states are cur at offset pos.
+// It is used directly by the DFA when its state cache overflows.
+func (m *setNFA) matchFrom(inputText []byte, pos int, cur stateSet, found *setMatches, b *budget) error {
+	next := newStateSet(m.numStates())
+	for i := pos; ; i++ {
+		if found.add(m, m.accepting(cur), i, len(inputText)) || i >= len(inputText) || cur.empty() {
+			return nil
+		}
+		if err := b.poll(i); err != nil {
+			return err
+		}
+		m.step(cur, inputText[i], next)
+		cur, next = next, cur
+	}
+}
+
+// setDFA is the lazy DFA of a setNFA, the counterpart of dfa for sets of
+// patterns.
+type setDFA struct {
+	lazyDFA
+	nfa *setNFA
+}
+
+// newSetDFA creates a lazy DFA for m that caches at most cacheSize bytes of states.
+func newSetDFA(m *setNFA, cacheSize int) *setDFA {
+	// Start states consume no byte; leave them out of the byte classes
+	var sets []*byteSet
+	for _, set := range m.sets {
+		if set != nil {
+			sets = append(sets, set)
+		}
+	}
+	return &setDFA{lazyDFA: newLazyDFA(m, sets, cacheSize), nfa: m}
+}
+
+// setDFAMatch is the search of setDFA.match.
+type setDFAMatch struct {
+	nfa       *setNFA
+	inputText []byte
+	found     *setMatches
+	b         *budget
+}
+
+func (m *setDFAMatch) visit(state *dfaState, i int) bool {
+	return m.found.add(m.nfa, state.accepting, i, len(m.inputText))
+}
+
+func (m *setDFAMatch) finish(i int, cur stateSet) error {
+	return m.nfa.matchFrom(m.inputText, i, cur, m.found, m.b)
+}
+
+// match runs the automaton over inputText and records the patterns that match
+// in found.
+func (d *setDFA) match(inputText []byte, found *setMatches, b *budget) error {
+	return d.run(inputText, &setDFAMatch{nfa: d.nfa, inputText: inputText, found: found, b: b}, b)
+}
@@ -0,0 +1,104 @@
+package regex
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// setReference returns the patterns that match input according to the standard
+// regexp package, checked one pattern at a time.
+func setReference(t *testing.T, patterns []string, input string) []int {
+	t.Helper()
+	var want []int
+	for i, pattern := range patterns {
+		if referenceMatch(t, pattern, input) {
+			want = append(want, i)
+		}
+	}
+	return want
+}
+
+func TestRegexSetMatches(t *testing.T) {
+	var patterns []string
+	inputSet := map[string]bool{}
+	for _, tt := range engineTests {
+		patterns = append(patterns, tt.pattern)
+		for _, input := range tt.inputs {
+			inputSet[input] = true
+		}
+	}
+	s := MustCompileSet(patterns)
+
+	for input := range inputSet {
+		got, err := s.Matches([]byte(input))
+		if err != nil {
+			t.Fatalf("Matches(%q) error = %v", input, err)
+		}
+		if want := setReference(t, patterns, input); !reflect.DeepEqual(got, want) {
+			t.Errorf("Matches(%q) = %v, want %v", input, got, want)
+		}
+
+		ok, err := s.Match([]byte(input))
+		if err != nil {
+			t.Fatalf("Match(%q) error = %v", input, err)
+		}
+		if ok != (len(got) > 0) {
+			t.Errorf("Match(%q) = %v, want %v", input, ok, len(got) > 0)
+		}
+	}
+}
+
+func TestRegexSetSmallCache(t *testing.T) {
+	patterns := []string{"a\\d+b", "^x", "[xy]+z$", "q?"}
+	s := MustCompileSet(patterns)
+
+	// With room for a single state every transition falls back to the NFA
+	d := newSetDFA(s.m, 1)
+	for _, input := range []string{"", "a12b", "xa1b", "zz xyz", "yyz!"} {
+		found := newSetMatches(len(patterns), false)
+		if err := d.match([]byte(input), found, unlimited()); err != nil {
+			t.Fatalf("match(%q) error = %v", input, err)
+		}
+		if got, want := found.indices(), setReference(t, patterns, input); !reflect.DeepEqual(got, want) {
+			t.Errorf("match(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestCompileSet(t *testing.T) {
+	s, err := CompileSet([]string{"a", "b+", "c"})
+	if err != nil {
+		t.Fatalf("CompileSet() error = %v", err)
+	}
+	if s.Len() != 3 || !reflect.DeepEqual(s.Patterns(), []string{"a", "b+", "c"}) {
+		t.Errorf("set holds %d patterns %q", s.Len(), s.Patterns())
+	}
+
+	if _, err := CompileSet([]string{"a", "[b"}); err == nil {
+		t.Error("CompileSet() with an invalid pattern returned no error")
+	}
+
+	empty := MustCompileSet(nil)
+	if got, err := empty.Matches([]byte("abc")); got != nil || err != nil {
+		t.Errorf("empty set Matches() = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestRegexSetLimits(t *testing.T) {
+	s := MustCompileSet([]string{"x", "y"})
+	input := make([]byte, 2*checkInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.MatchesContext(ctx, input); !errors.Is(err, context.Canceled) {
+		t.Errorf("MatchesContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	s.SetDeadline(time.Now().Add(-time.Second))
+	if _, err := s.Matches(input); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Matches() error = %v, want %v", err, ErrTimeout)
+	}
+}
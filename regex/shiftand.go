@@ -0,0 +1,102 @@
+package regex
+
+// maxShiftAndTokens is the longest pattern the bit-parallel engine can run: one
+// bit per token plus one bit for the start state must fit in a uint64.
+const maxShiftAndTokens = 63
+
+// shiftAnd is a bit-parallel (Shift-And) matcher for short patterns.
+//
+// Bit 0 of the state word is the start state and bit i+1 is set when token i was
+// just consumed, the same numbering as the position automaton. Advancing over a
+// byte is a handful of word operations no matter how many states are active.
+type shiftAnd struct {
+	masks  [256]uint64 // masks[b] has bit i+1 set if token i matches byte b
+	loops  uint64      // bits of tokens with +, which may consume again
+	accept uint64      // bit set once the whole pattern has matched
+
+	// Tokens with ? are skipped by filling runs of bits, following the extended
+	// Shift-And of Navarro and Raffinot. For each run of consecutive ? tokens,
+	// blockStart has the bit just before the run, blockEnd the last bit of the
+	// run and optional every bit of the run.
+	optional   uint64
+	blockStart uint64
+	blockEnd   uint64
+
+	anchoredStart bool
+	anchoredEnd   bool
+}
+
+// newShiftAnd builds the matcher. It returns nil if the pattern is too long or
+// has a multibyte token, which doesn't fit in one bit.
+func newShiftAnd(tokens []token, anchoredStart, anchoredEnd bool) *shiftAnd {
+	if len(tokens) > maxShiftAndTokens {
+		return nil
+	}
+	for _, tok := range tokens {
+		if tok.multibyte() {
+			return nil
+		}
+	}
+
+	m := &shiftAnd{
+		accept:        1 << len(tokens),
+		anchoredStart: anchoredStart,
+		anchoredEnd:   anchoredEnd,
+	}
+	for i, tok := range tokens {
+		bit := uint64(1) << (i + 1)
+		for b := 0; b < 256; b++ {
+			if matchToken(tok, byte(b)) {
+				m.masks[b] |= bit
+			}
+		}
+
+		switch tok.Quantifier {
+		case oneOrMore:
+			m.loops |= bit
+		case zeroOrOne:
+			m.optional |= bit
+			if i == 0 || tokens[i-1].Quantifier != zeroOrOne {
+				m.blockStart |= bit >> 1
+			}
+			if i == len(tokens)-1 || tokens[i+1].Quantifier != zeroOrOne {
+				m.blockEnd |= bit
+			}
+		}
+	}
+
+	return m
+}
+
+// skipOptional adds the states reachable by skipping ? tokens. Subtracting
+// blockStart borrows through the run from its first bit up to the lowest active
+// bit, which leaves every bit above it, up to blockEnd, set in the result.
+func (m *shiftAnd) skipOptional(d uint64) uint64 {
+	df := d | m.blockEnd
+	return d | (m.optional & (^(df - m.blockStart) ^ df))
+}
+
+// match reports whether the pattern matches anywhere in inputText.
+func (m *shiftAnd) match(inputText []byte, b *budget) (bool, error) {
+	d := m.skipOptional(1)
+	for i := 0; ; i++ {
+		if !m.anchoredEnd && d&m.accept != 0 {
+			return true, nil
+		}
+		if i >= len(inputText) {
+			return d&m.accept != 0, nil
+		}
+		if err := b.poll(i); err != nil {
+			return false, err
+		}
+
+		mask := m.masks[inputText[i]]
+		d = (d<<1)&mask | d&m.loops&mask
+		if !m.anchoredStart {
+			d |= 1 // a new match attempt starts at every offset
+		} else if d == 0 {
+			return false, nil
+		}
+		d = m.skipOptional(d)
+	}
+}
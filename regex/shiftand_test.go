@@ -0,0 +1,85 @@
+package regex
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestShiftAndMatch(t *testing.T) {
+	cases := append(engineTests[:len(engineTests):len(engineTests)], []struct {
+		pattern string
+		inputs  []string
+	}{
+		{"a?b?c?d", []string{"d", "abd", "acd", "bcd", "abcd", "cbd"}},
+		{"xa?b?yc?d?z", []string{"xyz", "xayz", "xbyz", "xabycdz", "xbaycz", "xyd"}},
+		{"^a?b?$", []string{"", "a", "b", "ab", "ba"}},
+		{"a+b?a+", []string{"aa", "aba", "abba"}},
+	}...)
+
+	for _, tt := range cases {
+		tokens, anchoredStart, anchoredEnd := compileForTest(t, tt.pattern)
+		m := newShiftAnd(tokens, anchoredStart, anchoredEnd)
+		if m == nil {
+			if hasMultibyte(tokens) {
+				// Multibyte tokens are left to the other engines
+				continue
+			}
+			t.Fatalf("newShiftAnd(%q) = nil", tt.pattern)
+		}
+		for _, input := range tt.inputs {
+			want := referenceMatch(t, tt.pattern, input)
+			if got := mustMatch(t)(m.match([]byte(input), unlimited())); got != want {
+				t.Errorf("shiftAnd.match(%q, %q) = %v, want %v", tt.pattern, input, got, want)
+			}
+		}
+	}
+}
+
+func TestShiftAndSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		line    string
+		want    bool
+	}{
+		{name: "short pattern", pattern: "\\d+ apples?", line: "12 apples", want: true},
+		{name: "63 tokens", pattern: strings.Repeat("a", 63), line: strings.Repeat("a", 70), want: true},
+		{name: "64 tokens", pattern: strings.Repeat("a", 64), line: strings.Repeat("a", 70), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compilePattern() error = %v", err)
+			}
+			if got := p.shiftAnd != nil; got != tt.want {
+				t.Errorf("uses shiftAnd = %v, want %v", got, tt.want)
+			}
+			if !mustMatch(t)(p.match(context.Background(), []byte(tt.line))) {
+				t.Errorf("match(%q) = false, want true", tt.line)
+			}
+		})
+	}
+}
+
+// compileForTest compiles the pattern and returns the parts the engines are built from.
+func compileForTest(t *testing.T, pattern string) ([]token, bool, bool) {
+	t.Helper()
+	p, err := compilePattern(pattern)
+	if err != nil {
+		t.Fatalf("compilePattern(%q) error = %v", pattern, err)
+	}
+	return p.tokens, p.anchoredStart, p.anchoredEnd
+}
+
+// hasMultibyte reports whether any of the tokens is multibyte.
+func hasMultibyte(tokens []token) bool {
+	for _, tok := range tokens {
+		if tok.multibyte() {
+			return true
+		}
+	}
+	return false
+}
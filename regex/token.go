@@ -0,0 +1,241 @@
+package regex
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	digits    = "0123456789"
+	wordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+)
+
+// tokenType represents the type of a pattern token.
+type tokenType int
+
+const (
+	literal      tokenType = iota // Single literal character: "a", "b"
+	digit                         // \d - digit character
+	word                          // \w - word character
+	charClass                     // [abc] - positive character class
+	negCharClass                  // [^abc] - negative character class
+	dot                           // . - any single UTF-8 character (except newline)
+)
+
+// quantifierType represents the quantifier applied to a token.
+type quantifierType int
+
+const (
+	none      quantifierType = iota // Exactly one (no quantifier)
+	oneOrMore                       // + (one or more)
+	zeroOrOne                       // ? (zero or one)
+)
+
+// token represents a single pattern matching unit with optional quantifier.
+type token struct {
+	Type       tokenType      // Type of the token
+	Value      string         // The pattern value (e.g., "a", "\\d", "abc" for char class)
+	Quantifier quantifierType // Quantifier type
+
+	set *charSet // Bytes matched by the token, precompiled by parseTokens
+}
+
+// parseTokens breaks a pattern string into individual tokens with quantifiers.
+//
+// Supported tokens:
+//   - Escape sequences: \d, \w, and \ followed by punctuation for that character
+//   - Character classes: [abc], [^abc]
+//   - Single characters: any other character
+//   - Quantifiers: + (one or more)
+//
+// It returns an error if:
+//   - A character class is not properly closed with ']'
+//   - A quantifier appears without a preceding character
+func parseTokens(pattern string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(pattern); {
+		var tok token
+		advance := 1
+
+		// Escape sequences: \d, \w, etc.
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			advance = 2
+
+			// Create token based on escape sequence type
+			switch pattern[i+1] {
+			case 'd':
+				tok = token{
+					Type:       digit,
+					Value:      "\\d",
+					Quantifier: none,
+				}
+			case 'w':
+				tok = token{
+					Type:       word,
+					Value:      "\\w",
+					Quantifier: none,
+				}
+			case '\\':
+				// Literal backslash: \\ represents a single '\'
+				tok = token{
+					Type:       literal,
+					Value:      "\\",
+					Quantifier: none,
+				}
+			default:
+				// Escaped punctuation stands for itself: \. \+ \[ \$ ...
+				if !isPunct(pattern[i+1]) {
+					return nil, fmt.Errorf("unsupported escape sequence: %s", pattern[i:i+2])
+				}
+				tok = token{
+					Type:       literal,
+					Value:      pattern[i+1 : i+2],
+					Quantifier: none,
+				}
+			}
+
+			// Character classes: [abc] or [^abc]
+		} else if pattern[i] == '[' {
+			j := i + 1
+			// Find the closing ']'
+			for j < len(pattern) && pattern[j] != ']' {
+				j++
+			}
+			// Check if we found a closing bracket
+			if j >= len(pattern) {
+				return nil, fmt.Errorf("unclosed character class starting at position %d", i)
+			}
+			advance = j + 1 - i
+			patternValue := pattern[i : j+1]
+
+			// Check if it's a negative character class
+			if len(patternValue) >= 3 && patternValue[1] == '^' {
+				tok = token{
+					Type:       negCharClass,
+					Value:      patternValue[2 : len(patternValue)-1], // Extract "abc" from "[^abc]"
+					Quantifier: none,
+				}
+			} else {
+				tok = token{
+					Type:       charClass,
+					Value:      patternValue[1 : len(patternValue)-1], // Extract "abc" from "[abc]"
+					Quantifier: none,
+				}
+			}
+
+			// Single literal character or metacharacter
+		} else {
+			// Check for invalid + at the beginning or after special chars
+			if pattern[i] == '+' {
+				return nil, fmt.Errorf("invalid pattern: + must follow a character at position %d", i)
+			}
+
+			// Dot metacharacter: matches any single character
+			if pattern[i] == '.' {
+				tok = token{
+					Type:       dot,
+					Value:      ".",
+					Quantifier: none,
+				}
+			} else {
+				tok = token{
+					Type:       literal,
+					Value:      pattern[i : i+1],
+					Quantifier: none,
+				}
+			}
+		}
+
+		// Check for quantifier after the current token
+		advance += parseQuantifierIfPresent(pattern, i+advance, &tok)
+
+		// Precompile the bytes the token matches so matching is a table lookup
+		tok.set = compileCharSet(tok)
+
+		tokens = append(tokens, tok)
+		i += advance
+	}
+
+	return tokens, nil
+}
+
+// isPunct reports whether c is ASCII punctuation, which may be escaped with a
+// backslash to match it literally.
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+// parseQuantifierIfPresent checks for a quantifier (+) after the current position
+// and updates the token accordingly. Returns number of characters consumed.
+//
+// Parameters:
+//   - pattern: the pattern string being parsed
+//   - pos: current position in the pattern to check for quantifier
+//   - token: pointer to the token to update with quantifier
+//
+// Returns the number of characters to advance (0 if no quantifier, 1 if quantifier found).
+func parseQuantifierIfPresent(pattern string, pos int, tok *token) int {
+	if pos < len(pattern) {
+		switch pattern[pos] {
+		case '+':
+			tok.Quantifier = oneOrMore
+			return 1
+		case '?':
+			tok.Quantifier = zeroOrOne
+			return 1
+		}
+	}
+	return 0
+}
+
+// multibyte reports whether the token matches multibyte UTF-8 characters,
+// which take more than one byte to match.
+func (tok token) multibyte() bool {
+	return tok.set != nil && tok.set.seqs != nil
+}
+
+// matchToken checks if a token matches a single byte.
+// It returns true if the byte matches the token's pattern.
+//
+// Tokens produced by parseTokens carry a precompiled byte set; tokens built by
+// hand fall back to comparing against the token's string value. A multibyte
+// token only matches its ASCII members this way.
+func matchToken(tok token, b byte) bool {
+	if tok.set != nil {
+		return tok.set.bytes.has(b)
+	}
+
+	switch tok.Type {
+	case literal:
+		return tok.Value == string([]byte{b})
+
+	case digit:
+		return strings.ContainsAny(string(b), digits)
+
+	case word:
+		return strings.ContainsAny(string(b), wordChars)
+
+	case charClass:
+		if tok.Value == "" {
+
+			return false
+		}
+		return strings.ContainsRune(tok.Value, rune(b))
+
+	case negCharClass:
+		if tok.Value == "" {
+			return false
+		}
+		return !strings.ContainsRune(tok.Value, rune(b))
+
+	case dot:
+		// Dot matches any character except newline
+		return b != '\n'
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,428 @@
+package regex
+
+import (
+	"testing"
+)
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    []token
+		wantErr bool
+	}{
+		// Literal characters
+		{
+			name:    "single literal character",
+			pattern: "a",
+			want: []token{
+				{Type: literal, Value: "a", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "multiple literal characters",
+			pattern: "abc",
+			want: []token{
+				{Type: literal, Value: "a", Quantifier: none},
+				{Type: literal, Value: "b", Quantifier: none},
+				{Type: literal, Value: "c", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		// Escape sequences
+		{
+			name:    "\\d digit pattern",
+			pattern: "\\d",
+			want: []token{
+				{Type: digit, Value: "\\d", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "\\w word pattern",
+			pattern: "\\w",
+			want: []token{
+				{Type: word, Value: "\\w", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "\\\\ literal backslash",
+			pattern: "\\\\",
+			want: []token{
+				{Type: literal, Value: "\\", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "\\. escaped punctuation",
+			pattern: "a\\.\\+\\$",
+			want: []token{
+				{Type: literal, Value: "a", Quantifier: none},
+				{Type: literal, Value: ".", Quantifier: none},
+				{Type: literal, Value: "+", Quantifier: none},
+				{Type: literal, Value: "$", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "non-ASCII literal is one token per byte",
+			pattern: "é",
+			want: []token{
+				{Type: literal, Value: "\xc3", Quantifier: none},
+				{Type: literal, Value: "\xa9", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		// Character classes
+		{
+			name:    "[abc] positive character class",
+			pattern: "[abc]",
+			want: []token{
+				{Type: charClass, Value: "abc", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "[^abc] negative character class",
+			pattern: "[^abc]",
+			want: []token{
+				{Type: negCharClass, Value: "abc", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "non-ASCII character in character class",
+			pattern: "caf[é]",
+			want: []token{
+				{Type: literal, Value: "c", Quantifier: none},
+				{Type: literal, Value: "a", Quantifier: none},
+				{Type: literal, Value: "f", Quantifier: none},
+				{Type: charClass, Value: "é", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "non-ASCII character in negated character class",
+			pattern: "[^aé]+",
+			want: []token{
+				{Type: negCharClass, Value: "aé", Quantifier: oneOrMore},
+			},
+			wantErr: false,
+		},
+		// Quantifiers
+		{
+			name:    "a+ with quantifier",
+			pattern: "a+",
+			want: []token{
+				{Type: literal, Value: "a", Quantifier: oneOrMore},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "\\d+ with quantifier",
+			pattern: "\\d+",
+			want: []token{
+				{Type: digit, Value: "\\d", Quantifier: oneOrMore},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "[abc]+ character class with quantifier",
+			pattern: "[abc]+",
+			want: []token{
+				{Type: charClass, Value: "abc", Quantifier: oneOrMore},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "a? with zero-or-one quantifier",
+			pattern: "a?",
+			want: []token{
+				{Type: literal, Value: "a", Quantifier: zeroOrOne},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "\\d? with zero-or-one quantifier",
+			pattern: "\\d?",
+			want: []token{
+				{Type: digit, Value: "\\d", Quantifier: zeroOrOne},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "[abc]? character class with zero-or-one quantifier",
+			pattern: "[abc]?",
+			want: []token{
+				{Type: charClass, Value: "abc", Quantifier: zeroOrOne},
+			},
+			wantErr: false,
+		},
+		{
+			name:    ". dot metacharacter",
+			pattern: ".",
+			want: []token{
+				{Type: dot, Value: ".", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    ".+ dot with quantifier",
+			pattern: ".+",
+			want: []token{
+				{Type: dot, Value: ".", Quantifier: oneOrMore},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "d.g pattern with dot",
+			pattern: "d.g",
+			want: []token{
+				{Type: literal, Value: "d", Quantifier: none},
+				{Type: dot, Value: ".", Quantifier: none},
+				{Type: literal, Value: "g", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		// Complex patterns
+		{
+			name:    "ca+ts pattern",
+			pattern: "ca+ts",
+			want: []token{
+				{Type: literal, Value: "c", Quantifier: none},
+				{Type: literal, Value: "a", Quantifier: oneOrMore},
+				{Type: literal, Value: "t", Quantifier: none},
+				{Type: literal, Value: "s", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "\\d+ apple pattern",
+			pattern: "\\d+ apple",
+			want: []token{
+				{Type: digit, Value: "\\d", Quantifier: oneOrMore},
+				{Type: literal, Value: " ", Quantifier: none},
+				{Type: literal, Value: "a", Quantifier: none},
+				{Type: literal, Value: "p", Quantifier: none},
+				{Type: literal, Value: "p", Quantifier: none},
+				{Type: literal, Value: "l", Quantifier: none},
+				{Type: literal, Value: "e", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "a\\\\b pattern (literal backslash between chars)",
+			pattern: "a\\\\b",
+			want: []token{
+				{Type: literal, Value: "a", Quantifier: none},
+				{Type: literal, Value: "\\", Quantifier: none},
+				{Type: literal, Value: "b", Quantifier: none},
+			},
+			wantErr: false,
+		},
+		// Error cases
+		{
+			name:    "unclosed character class",
+			pattern: "[abc",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "+ without preceding character",
+			pattern: "+abc",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported escape sequence",
+			pattern: "\\x",
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTokens(tt.pattern)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseTokens() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && !equalTokenSlices(got, tt.want) {
+				t.Errorf("parseTokens() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchToken(t *testing.T) {
+	tests := []struct {
+		name string
+		tok  token
+		b    byte
+		want bool
+	}{
+		// Literal tokens
+		{
+			name: "literal 'a' matches 'a'",
+			tok:  token{Type: literal, Value: "a"},
+			b:    'a',
+			want: true,
+		},
+		{
+			name: "literal 'a' does not match 'b'",
+			tok:  token{Type: literal, Value: "a"},
+			b:    'b',
+			want: false,
+		},
+		{
+			name: "literal '\\' matches '\\'",
+			tok:  token{Type: literal, Value: "\\"},
+			b:    '\\',
+			want: true,
+		},
+		{
+			name: "literal '\\' does not match 'a'",
+			tok:  token{Type: literal, Value: "\\"},
+			b:    'a',
+			want: false,
+		},
+		// Digit tokens
+		{
+			name: "\\d matches '5'",
+			tok:  token{Type: digit, Value: "\\d"},
+			b:    '5',
+			want: true,
+		},
+		{
+			name: "\\d does not match 'a'",
+			tok:  token{Type: digit, Value: "\\d"},
+			b:    'a',
+			want: false,
+		},
+		// Word tokens
+		{
+			name: "\\w matches 'a'",
+			tok:  token{Type: word, Value: "\\w"},
+			b:    'a',
+			want: true,
+		},
+		{
+			name: "\\w matches '5'",
+			tok:  token{Type: word, Value: "\\w"},
+			b:    '5',
+			want: true,
+		},
+		{
+			name: "\\w matches '_'",
+			tok:  token{Type: word, Value: "\\w"},
+			b:    '_',
+			want: true,
+		},
+		{
+			name: "\\w does not match '!'",
+			tok:  token{Type: word, Value: "\\w"},
+			b:    '!',
+			want: false,
+		},
+		// charClass tokens
+		{
+			name: "[abc] matches 'a'",
+			tok:  token{Type: charClass, Value: "abc"},
+			b:    'a',
+			want: true,
+		},
+		{
+			name: "[abc] matches 'c'",
+			tok:  token{Type: charClass, Value: "abc"},
+			b:    'c',
+			want: true,
+		},
+		{
+			name: "[abc] does not match 'z'",
+			tok:  token{Type: charClass, Value: "abc"},
+			b:    'z',
+			want: false,
+		},
+		// negCharClass tokens
+		{
+			name: "[^abc] matches 'z'",
+			tok:  token{Type: negCharClass, Value: "abc"},
+			b:    'z',
+			want: true,
+		},
+		{
+			name: "[^abc] does not match 'a'",
+			tok:  token{Type: negCharClass, Value: "abc"},
+			b:    'a',
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchToken(tt.tok, tt.b)
+			if got != tt.want {
+				t.Errorf("matchToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Helper function to compare two slices of tokens
+func equalTokenSlices(a, b []token) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || a[i].Value != b[i].Value || a[i].Quantifier != b[i].Quantifier {
+			return false
+		}
+	}
+	return true
+}
+
+// BenchmarkMatchToken compares the string comparison fallback used by hand-built
+// tokens with the lookup table that parseTokens precompiles.
+func BenchmarkMatchToken(b *testing.B) {
+	patterns := []struct {
+		name    string
+		pattern string
+	}{
+		{"Digit", "\\d"},
+		{"Word", "\\w"},
+		{"CharClass", "[aeiouxyz]"},
+		{"NegCharClass", "[^aeiouxyz]"},
+		{"Dot", "."},
+	}
+	input := []byte("The quick brown fox jumps over the lazy dog 0123456789 _!?")
+
+	for _, p := range patterns {
+		tokens, err := parseTokens(p.pattern)
+		if err != nil {
+			b.Fatalf("parseTokens(%q) error = %v", p.pattern, err)
+		}
+		compiled := tokens[0]
+		uncompiled := compiled
+		uncompiled.set = nil
+
+		b.Run(p.name+"/string", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, c := range input {
+					matchToken(uncompiled, c)
+				}
+			}
+		})
+		b.Run(p.name+"/table", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, c := range input {
+					matchToken(compiled, c)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,720 @@
+// Package regexp is a drop-in replacement for the standard library's regexp
+// package backed by the mygrep matcher in package regex.
+//
+// It has the same functions and the same Regexp method set, so code can switch
+// by changing its import path. Patterns use the regex package's syntax, which is
+// a subset of the standard one. Compile rejects standard syntax outside that
+// subset, so no pattern silently means something else than it would with the
+// standard package on UTF-8 text. The one difference is on input that isn't
+// valid UTF-8: the standard package matches each invalid byte as U+FFFD, but
+// here the dot and negated classes don't match it. The backtracking step limit
+// of package regex is turned off, since its memoization already keeps searches
+// polynomial, so the methods here never fail.
+package regexp
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// Regexp is the representation of a compiled regular expression.
+// A Regexp is safe for concurrent use by multiple goroutines, except for
+// configuration methods, such as Longest.
+type Regexp struct {
+	re      *regex.Regexp
+	longest bool
+}
+
+// Compile parses a regular expression and returns, if successful, a Regexp
+// object that can be used to match against text.
+//
+// Standard syntax beyond the regex package's subset, such as alternation,
+// groups, the * and {n,m} repetitions or class ranges, is rejected with an
+// error rather than matched literally.
+func Compile(expr string) (*Regexp, error) {
+	if err := checkSyntax(expr); err != nil {
+		return nil, err
+	}
+	re, err := regex.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	re.SetBacktrackLimit(0)
+	return &Regexp{re: re}, nil
+}
+
+// checkSyntax returns an error if expr uses standard syntax that the regex
+// package would read differently: it treats characters such as | ( ) * { as
+// literals, where the standard package gives them a meaning.
+func checkSyntax(expr string) error {
+	unsupported := func(what string) error {
+		return errors.New("regexp: error parsing regexp: " + what + " not supported: `" + expr + "`")
+	}
+
+	atom := false // a quantifier may follow
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\':
+			if i == len(expr)-1 {
+				return errors.New("regexp: error parsing regexp: trailing backslash at end of expression: ``")
+			}
+			i++ // the regex parser checks the escape itself
+			atom = true
+		case c == '[':
+			j := i + 1
+			if j < len(expr) && expr[j] == '^' {
+				j++
+			}
+			if j < len(expr) && expr[j] == ']' {
+				return unsupported("] as the first member of a character class")
+			}
+			for first := j; j < len(expr) && expr[j] != ']'; j++ {
+				switch {
+				case expr[j] == '\\':
+					return unsupported("escape sequence in a character class")
+				case expr[j] == '[' && j+1 < len(expr) && strings.ContainsRune(":.=", rune(expr[j+1])):
+					return unsupported("named character class")
+				case expr[j] == '-' && j > first && j+1 < len(expr) && expr[j+1] != ']':
+					return unsupported("character class range")
+				}
+			}
+			i = j // an unclosed class is reported by the regex parser
+			atom = true
+		case c == '+' || c == '?':
+			if !atom {
+				return unsupported("repetition operator without an argument or after another")
+			}
+			if i > 0 && expr[i-1] >= utf8.RuneSelf {
+				return unsupported("repetition of a multibyte character")
+			}
+			atom = false
+		case c == '^' && i > 0, c == '$' && i < len(expr)-1:
+			return unsupported("anchor inside the pattern")
+		case strings.IndexByte("|*(){", c) >= 0:
+			return unsupported("metacharacter " + string(c))
+		default:
+			atom = c != '^' && c != '$'
+		}
+	}
+	return nil
+}
+
+// CompilePOSIX is like Compile but makes the Regexp use leftmost-longest
+// matching, as POSIX specifies.
+func CompilePOSIX(expr string) (*Regexp, error) {
+	re, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	re.Longest()
+	return re, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be parsed.
+func MustCompile(str string) *Regexp {
+	re, err := Compile(str)
+	if err != nil {
+		panic(`regexp: Compile(` + strconv.Quote(str) + `): ` + err.Error())
+	}
+	return re
+}
+
+// MustCompilePOSIX is like CompilePOSIX but panics if the expression cannot be parsed.
+func MustCompilePOSIX(str string) *Regexp {
+	re, err := CompilePOSIX(str)
+	if err != nil {
+		panic(`regexp: CompilePOSIX(` + strconv.Quote(str) + `): ` + err.Error())
+	}
+	return re
+}
+
+// MatchString reports whether the string s contains any match of the pattern.
+func MatchString(pattern string, s string) (matched bool, err error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+// Match reports whether the byte slice b contains any match of the pattern.
+func Match(pattern string, b []byte) (matched bool, err error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.Match(b), nil
+}
+
+// MatchReader reports whether the text returned by the RuneReader contains any
+// match of the pattern.
+func MatchReader(pattern string, r io.RuneReader) (matched bool, err error) {
+	re, err := Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchReader(r), nil
+}
+
+// QuoteMeta returns a string that escapes all regular expression metacharacters
+// inside the argument text; the returned string is a regular expression matching
+// the literal text.
+func QuoteMeta(s string) string {
+	var b strings.Builder
+	b.Grow(2 * len(s))
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(`\.+*?()|[]{}^$`, s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// String returns the source text used to compile the regular expression.
+func (re *Regexp) String() string {
+	return re.re.String()
+}
+
+// Copy returns a new Regexp object copied from re.
+//
+// Deprecated: In earlier releases of the standard library, when using a Regexp
+// in multiple goroutines, giving each goroutine its own copy helped to avoid
+// lock contention. It is kept for compatibility only.
+func (re *Regexp) Copy() *Regexp {
+	c := MustCompile(re.String())
+	if re.longest {
+		c.Longest()
+	}
+	return c
+}
+
+// Longest makes future searches prefer leftmost-longest matches.
+// This method modifies the Regexp and may not be called concurrently with any
+// other methods.
+func (re *Regexp) Longest() {
+	re.longest = true
+	re.re.Longest()
+}
+
+// NumSubexp returns the number of parenthesized subexpressions in this Regexp.
+func (re *Regexp) NumSubexp() int {
+	return re.re.NumSubexp()
+}
+
+// SubexpNames returns the names of the parenthesized subexpressions in this
+// Regexp. names[0] is always the empty string.
+func (re *Regexp) SubexpNames() []string {
+	return re.re.SubexpNames()
+}
+
+// SubexpIndex returns the index of the first subexpression with the given name,
+// or -1 if there is no subexpression with that name.
+func (re *Regexp) SubexpIndex(name string) int {
+	if name != "" {
+		for i, s := range re.SubexpNames() {
+			if name == s {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// LiteralPrefix returns a literal string that must begin any match of the
+// regular expression re. It returns the boolean true if the literal string
+// comprises the entire regular expression.
+func (re *Regexp) LiteralPrefix() (prefix string, complete bool) {
+	return re.re.LiteralPrefix()
+}
+
+// MarshalText implements encoding.TextMarshaler. The output matches that of
+// calling the String method.
+func (re *Regexp) MarshalText() ([]byte, error) {
+	return []byte(re.String()), nil
+}
+
+// AppendText implements encoding.TextAppender.
+func (re *Regexp) AppendText(b []byte) ([]byte, error) {
+	return append(b, re.String()...), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler by calling Compile on the
+// encoded value.
+func (re *Regexp) UnmarshalText(text []byte) error {
+	newRE, err := Compile(string(text))
+	if err != nil {
+		return err
+	}
+	*re = *newRE
+	return nil
+}
+
+// The methods below drop the errors returned by package regex. Those only report
+// aborted searches, and Compile turns off every limit that could abort one.
+
+// find returns the leftmost match in b, in the layout of FindSubmatchIndex.
+func (re *Regexp) find(b []byte) []int {
+	loc, _ := re.re.FindSubmatchIndex(b)
+	return loc
+}
+
+// findAll returns up to n matches in b, or all of them if n is negative.
+func (re *Regexp) findAll(b []byte, n int) [][]int {
+	if n < 0 {
+		n = len(b) + 1
+	}
+	locs, _ := re.re.FindAllIndex(b, n)
+	return locs
+}
+
+// MatchString reports whether the string s contains any match of the regular
+// expression re.
+func (re *Regexp) MatchString(s string) bool {
+	return re.Match([]byte(s))
+}
+
+// Match reports whether the byte slice b contains any match of the regular
+// expression re.
+func (re *Regexp) Match(b []byte) bool {
+	ok, _ := re.re.Match(b)
+	return ok
+}
+
+// MatchReader reports whether the text returned by the RuneReader contains any
+// match of the regular expression re. The whole text is read before matching.
+func (re *Regexp) MatchReader(r io.RuneReader) bool {
+	return re.Match(readRunes(r))
+}
+
+// readRunes reads r to the end and returns the UTF-8 encoding of the runes read.
+func readRunes(r io.RuneReader) []byte {
+	var b []byte
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return b
+		}
+		b = utf8.AppendRune(b, c)
+	}
+}
+
+// Find returns a slice holding the text of the leftmost match in b of the
+// regular expression. A return value of nil indicates no match.
+func (re *Regexp) Find(b []byte) []byte {
+	loc := re.find(b)
+	if loc == nil {
+		return nil
+	}
+	return b[loc[0]:loc[1]:loc[1]]
+}
+
+// FindIndex returns a two-element slice of integers defining the location of the
+// leftmost match in b of the regular expression. The match itself is at
+// b[loc[0]:loc[1]]. A return value of nil indicates no match.
+func (re *Regexp) FindIndex(b []byte) (loc []int) {
+	loc = re.find(b)
+	if loc == nil {
+		return nil
+	}
+	return loc[0:2]
+}
+
+// FindString returns a string holding the text of the leftmost match in s of the
+// regular expression. If there is no match, the return value is an empty string,
+// but it will also be empty if the regular expression successfully matches an
+// empty string. Use FindStringIndex or FindStringSubmatch if it is necessary to
+// distinguish these cases.
+func (re *Regexp) FindString(s string) string {
+	loc := re.find([]byte(s))
+	if loc == nil {
+		return ""
+	}
+	return s[loc[0]:loc[1]]
+}
+
+// FindStringIndex returns a two-element slice of integers defining the location
+// of the leftmost match in s of the regular expression. The match itself is at
+// s[loc[0]:loc[1]]. A return value of nil indicates no match.
+func (re *Regexp) FindStringIndex(s string) (loc []int) {
+	return re.FindIndex([]byte(s))
+}
+
+// FindReaderIndex returns a two-element slice of integers defining the location
+// of the leftmost match of the regular expression in text read from the
+// RuneReader. A return value of nil indicates no match.
+func (re *Regexp) FindReaderIndex(r io.RuneReader) (loc []int) {
+	return re.FindIndex(readRunes(r))
+}
+
+// FindSubmatch returns a slice of slices holding the text of the leftmost match
+// of the regular expression in b and the matches, if any, of its
+// subexpressions. A return value of nil indicates no match.
+func (re *Regexp) FindSubmatch(b []byte) [][]byte {
+	loc := re.find(b)
+	if loc == nil {
+		return nil
+	}
+	ret := make([][]byte, 1+re.NumSubexp())
+	for i := range ret {
+		if 2*i < len(loc) && loc[2*i] >= 0 {
+			ret[i] = b[loc[2*i]:loc[2*i+1]:loc[2*i+1]]
+		}
+	}
+	return ret
+}
+
+// FindSubmatchIndex returns a slice holding the index pairs identifying the
+// leftmost match of the regular expression in b and the matches, if any, of its
+// subexpressions. A return value of nil indicates no match.
+func (re *Regexp) FindSubmatchIndex(b []byte) []int {
+	return re.pad(re.find(b))
+}
+
+// FindStringSubmatch returns a slice of strings holding the text of the leftmost
+// match of the regular expression in s and the matches, if any, of its
+// subexpressions. A return value of nil indicates no match.
+func (re *Regexp) FindStringSubmatch(s string) []string {
+	loc := re.find([]byte(s))
+	if loc == nil {
+		return nil
+	}
+	ret := make([]string, 1+re.NumSubexp())
+	for i := range ret {
+		if 2*i < len(loc) && loc[2*i] >= 0 {
+			ret[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return ret
+}
+
+// FindStringSubmatchIndex returns a slice holding the index pairs identifying
+// the leftmost match of the regular expression in s and the matches, if any, of
+// its subexpressions. A return value of nil indicates no match.
+func (re *Regexp) FindStringSubmatchIndex(s string) []int {
+	return re.FindSubmatchIndex([]byte(s))
+}
+
+// FindReaderSubmatchIndex returns a slice holding the index pairs identifying
+// the leftmost match of the regular expression of text read by the RuneReader,
+// and the matches, if any, of its subexpressions. A return value of nil
+// indicates no match.
+func (re *Regexp) FindReaderSubmatchIndex(r io.RuneReader) []int {
+	return re.FindSubmatchIndex(readRunes(r))
+}
+
+// pad extends a match location to hold a pair for every subexpression, with -1
+// for those that did not participate.
+func (re *Regexp) pad(a []int) []int {
+	if a == nil {
+		return nil
+	}
+	n := (1 + re.NumSubexp()) * 2
+	for len(a) < n {
+		a = append(a, -1)
+	}
+	return a
+}
+
+// FindAll is the 'All' version of Find; it returns a slice of all successive
+// matches of the expression. A return value of nil indicates no match.
+func (re *Regexp) FindAll(b []byte, n int) [][]byte {
+	var result [][]byte
+	for _, loc := range re.findAll(b, n) {
+		result = append(result, b[loc[0]:loc[1]:loc[1]])
+	}
+	return result
+}
+
+// FindAllIndex is the 'All' version of FindIndex; it returns a slice of all
+// successive matches of the expression. A return value of nil indicates no match.
+func (re *Regexp) FindAllIndex(b []byte, n int) [][]int {
+	var result [][]int
+	for _, loc := range re.findAll(b, n) {
+		result = append(result, loc[0:2])
+	}
+	return result
+}
+
+// FindAllString is the 'All' version of FindString; it returns a slice of all
+// successive matches of the expression. A return value of nil indicates no match.
+func (re *Regexp) FindAllString(s string, n int) []string {
+	var result []string
+	for _, loc := range re.findAll([]byte(s), n) {
+		result = append(result, s[loc[0]:loc[1]])
+	}
+	return result
+}
+
+// FindAllStringIndex is the 'All' version of FindStringIndex; it returns a slice
+// of all successive matches of the expression. A return value of nil indicates
+// no match.
+func (re *Regexp) FindAllStringIndex(s string, n int) [][]int {
+	return re.FindAllIndex([]byte(s), n)
+}
+
+// FindAllSubmatch is the 'All' version of FindSubmatch; it returns a slice of
+// all successive matches of the expression. A return value of nil indicates no
+// match.
+func (re *Regexp) FindAllSubmatch(b []byte, n int) [][][]byte {
+	var result [][][]byte
+	for _, loc := range re.findAll(b, n) {
+		loc = re.pad(loc)
+		slice := make([][]byte, len(loc)/2)
+		for j := range slice {
+			if loc[2*j] >= 0 {
+				slice[j] = b[loc[2*j]:loc[2*j+1]:loc[2*j+1]]
+			}
+		}
+		result = append(result, slice)
+	}
+	return result
+}
+
+// FindAllSubmatchIndex is the 'All' version of FindSubmatchIndex; it returns a
+// slice of all successive matches of the expression. A return value of nil
+// indicates no match.
+func (re *Regexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
+	var result [][]int
+	for _, loc := range re.findAll(b, n) {
+		result = append(result, re.pad(loc))
+	}
+	return result
+}
+
+// FindAllStringSubmatch is the 'All' version of FindStringSubmatch; it returns a
+// slice of all successive matches of the expression. A return value of nil
+// indicates no match.
+func (re *Regexp) FindAllStringSubmatch(s string, n int) [][]string {
+	var result [][]string
+	for _, loc := range re.findAll([]byte(s), n) {
+		loc = re.pad(loc)
+		slice := make([]string, len(loc)/2)
+		for j := range slice {
+			if loc[2*j] >= 0 {
+				slice[j] = s[loc[2*j]:loc[2*j+1]]
+			}
+		}
+		result = append(result, slice)
+	}
+	return result
+}
+
+// FindAllStringSubmatchIndex is the 'All' version of FindStringSubmatchIndex; it
+// returns a slice of all successive matches of the expression. A return value of
+// nil indicates no match.
+func (re *Regexp) FindAllStringSubmatchIndex(s string, n int) [][]int {
+	return re.FindAllSubmatchIndex([]byte(s), n)
+}
+
+// Split slices s around the matches of the expression and returns the pieces
+// between them. n limits the number of pieces: with n > 0 at most n are
+// returned, the last one holding the rest of s unsplit, n == 0 returns nil, and
+// n < 0 returns every piece.
+func (re *Regexp) Split(s string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	if s == "" {
+		// As in the standard package, only the empty expression leaves nothing
+		if re.String() == "" {
+			return []string{}
+		}
+		return []string{""}
+	}
+
+	cuts := re.FindAllStringIndex(s, n)
+	if len(cuts) > 0 && cuts[0][1] == 0 {
+		// An empty match at the start doesn't cut off an empty first piece
+		cuts = cuts[1:]
+	}
+	if n > 0 && len(cuts) > n-1 {
+		cuts = cuts[:n-1]
+	}
+
+	pieces := make([]string, 0, len(cuts)+1)
+	from := 0
+	for _, cut := range cuts {
+		pieces = append(pieces, s[from:cut[0]])
+		from = cut[1]
+	}
+	// Nor does an empty match at the end cut off an empty last piece
+	if len(cuts) == 0 || cuts[len(cuts)-1][0] < len(s) {
+		pieces = append(pieces, s[from:])
+	}
+	return pieces
+}
+
+// replaceAll calls repl for every match in src and returns src with each match
+// replaced by what repl appended.
+func (re *Regexp) replaceAll(src []byte, repl func(dst []byte, loc []int) []byte) []byte {
+	var buf []byte
+	lastMatchEnd := 0
+	for _, loc := range re.findAll(src, -1) {
+		buf = append(buf, src[lastMatchEnd:loc[0]]...)
+		buf = repl(buf, re.pad(loc))
+		lastMatchEnd = loc[1]
+	}
+	return append(buf, src[lastMatchEnd:]...)
+}
+
+// ReplaceAllString returns a copy of src, replacing matches of the Regexp with
+// the replacement string repl. Inside repl, $ signs are interpreted as in Expand.
+func (re *Regexp) ReplaceAllString(src, repl string) string {
+	b := re.replaceAll([]byte(src), func(dst []byte, loc []int) []byte {
+		return re.expand(dst, repl, nil, src, loc)
+	})
+	return string(b)
+}
+
+// ReplaceAllLiteralString returns a copy of src, replacing matches of the Regexp
+// with the replacement string repl. The replacement repl is substituted directly,
+// without using Expand.
+func (re *Regexp) ReplaceAllLiteralString(src, repl string) string {
+	return string(re.replaceAll([]byte(src), func(dst []byte, loc []int) []byte {
+		return append(dst, repl...)
+	}))
+}
+
+// ReplaceAllStringFunc returns a copy of src in which all matches of the Regexp
+// have been replaced by the return value of function repl applied to the matched
+// substring. The replacement returned by repl is substituted directly, without
+// using Expand.
+func (re *Regexp) ReplaceAllStringFunc(src string, repl func(string) string) string {
+	return string(re.replaceAll([]byte(src), func(dst []byte, loc []int) []byte {
+		return append(dst, repl(src[loc[0]:loc[1]])...)
+	}))
+}
+
+// ReplaceAll returns a copy of src, replacing matches of the Regexp with the
+// replacement text repl. Inside repl, $ signs are interpreted as in Expand.
+func (re *Regexp) ReplaceAll(src, repl []byte) []byte {
+	return re.replaceAll(src, func(dst []byte, loc []int) []byte {
+		return re.expand(dst, string(repl), src, "", loc)
+	})
+}
+
+// ReplaceAllLiteral returns a copy of src, replacing matches of the Regexp with
+// the replacement bytes repl. The replacement repl is substituted directly,
+// without using Expand.
+func (re *Regexp) ReplaceAllLiteral(src, repl []byte) []byte {
+	return re.replaceAll(src, func(dst []byte, loc []int) []byte {
+		return append(dst, repl...)
+	})
+}
+
+// ReplaceAllFunc returns a copy of src in which all matches of the Regexp have
+// been replaced by the return value of function repl applied to the matched byte
+// slice. The replacement returned by repl is substituted directly, without using
+// Expand.
+func (re *Regexp) ReplaceAllFunc(src []byte, repl func([]byte) []byte) []byte {
+	return re.replaceAll(src, func(dst []byte, loc []int) []byte {
+		return append(dst, repl(src[loc[0]:loc[1]])...)
+	})
+}
+
+// Expand appends template to dst, with the variables in it replaced by the
+// submatches of src that match, as returned by FindSubmatchIndex, locates.
+//
+// A variable is $name or ${name}, where name is made of letters, digits and
+// underscores. A number names the submatch with that index and any other name
+// a named group. Variables that name no group, or a group that didn't take
+// part in the match, are replaced by nothing. $$ stands for a literal $, and a
+// $ that starts no variable is copied as is.
+func (re *Regexp) Expand(dst []byte, template []byte, src []byte, match []int) []byte {
+	return re.expand(dst, string(template), src, "", match)
+}
+
+// ExpandString is like Expand but the template and source are strings.
+func (re *Regexp) ExpandString(dst []byte, template string, src string, match []int) []byte {
+	return re.expand(dst, template, nil, src, match)
+}
+
+// expand appends template to dst with its variables replaced by the
+// submatches of bsrc, or of src if bsrc is nil.
+func (re *Regexp) expand(dst []byte, template string, bsrc []byte, src string, match []int) []byte {
+	for {
+		i := strings.IndexByte(template, '$')
+		if i < 0 {
+			return append(dst, template...)
+		}
+		dst = append(dst, template[:i]...)
+		template = template[i+1:]
+
+		if strings.HasPrefix(template, "$") {
+			dst = append(dst, '$')
+			template = template[1:]
+			continue
+		}
+		name, rest, ok := templateVar(template)
+		if !ok {
+			dst = append(dst, '$')
+			continue
+		}
+		template = rest
+
+		group, ok := groupNumber(name)
+		if !ok {
+			group = re.SubexpIndex(name)
+		}
+		if group < 0 || 2*group+1 >= len(match) || match[2*group] < 0 {
+			continue
+		}
+		lo, hi := match[2*group], match[2*group+1]
+		if bsrc != nil {
+			dst = append(dst, bsrc[lo:hi]...)
+		} else {
+			dst = append(dst, src[lo:hi]...)
+		}
+	}
+}
+
+// templateVar splits the name of a variable off the start of template, which
+// follows the variable's $. It reports false if template starts with no name,
+// or with a brace that isn't closed right after the name.
+func templateVar(template string) (name, rest string, ok bool) {
+	braced := strings.HasPrefix(template, "{")
+	if braced {
+		template = template[1:]
+	}
+	end := strings.IndexFunc(template, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if end < 0 {
+		end = len(template)
+	}
+	if end == 0 {
+		return "", "", false
+	}
+	name, rest = template[:end], template[end:]
+	if braced {
+		if !strings.HasPrefix(rest, "}") {
+			return "", "", false
+		}
+		rest = rest[1:]
+	}
+	return name, rest, true
+}
+
+// groupNumber returns the index of the submatch a numeric variable name refers
+// to. Names with a leading zero or more than nine digits count as group names,
+// as in the standard package.
+func groupNumber(name string) (int, bool) {
+	if len(name) > 9 || (len(name) > 1 && name[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] < '0' || name[i] > '9' {
+			return 0, false
+		}
+	}
+	n, _ := strconv.Atoi(name)
+	return n, true
+}
@@ -0,0 +1,260 @@
+package regexp
+
+import (
+	"reflect"
+	stdregexp "regexp"
+	"strings"
+	"testing"
+)
+
+// compatTests are run through both this package and the standard library, which
+// must agree on every method. The patterns stay within the shared syntax.
+var compatTests = []struct {
+	pattern string
+	inputs  []string
+}{
+	{"abc", []string{"", "abc", "xabcx", "abcabc", "ab"}},
+	{`\d+`, []string{"", "a1b22c333", "no digits", "42"}},
+	{`\w+`, []string{"hello world", "  ", "foo_bar-baz"}},
+	{"a?", []string{"", "a", "baaac", "bb"}},
+	{"a+", []string{"baaac", "aaa", "b"}},
+	{"[abc]+", []string{"xxabcaxx", "cab", "d"}},
+	{"[^abc]+", []string{"xxabcaxx", "abc", "defabc", "éaé", "日本語"}},
+	{"[^x]", []string{"é", "xé", "x"}},
+	{"^ab", []string{"abab", "cab", ""}},
+	{"ab$", []string{"abab", "abc", "ab"}},
+	{"^$", []string{"", "x"}},
+	{"a.c", []string{"abcadcaxc", "ac", "a\nc", "aéc", "a日本c"}},
+	{".", []string{"é", "日本", "x"}},
+	{"^.$", []string{"é", "ab", "😀"}},
+	{"a.?b", []string{"aéb", "a日b", "ab", "aééb"}},
+	{`\.`, []string{"a.b.c", "abc"}},
+	{"café", []string{"un café!", "cafe", "cafécafé"}},
+	{"caf[éè]+", []string{"un café!", "cafèé", "cafe"}},
+	{"[^é]", []string{"é", "éa", "ééé", ""}},
+	{"", []string{"", "abc", "é"}},
+	{`\(a\|b\)\*\{2}`, []string{"(a|b)*{2}", "ab", "x(a|b)*{2}x"}},
+	{"[a-]+", []string{"a-b", "---", "b"}},
+}
+
+// unsupportedPatterns are valid in the standard library but outside the regex
+// package's syntax. Compiling them must fail rather than match them literally.
+var unsupportedPatterns = []string{
+	"a|b",
+	"ab*",
+	"(ab)",
+	"a{2}",
+	"[a-c]",
+	"[^0-9]",
+	`[\d]`,
+	"[[:alpha:]]",
+	"[]a]",
+	"a+?",
+	"a??",
+	"^+",
+	"a^b",
+	"a$b",
+	"é+",
+}
+
+// invalidPatterns are rejected by both packages.
+var invalidPatterns = []string{"[abc", `a\`, `\`, "+a", "a++"}
+
+var replacements = []string{"", "<$0>", "${0}x", "$$", "$1", "$name", "$", "${0", "$0x", "$00", "${name}", "$1234567890", "$é!", "${}"}
+
+func TestCompat(t *testing.T) {
+	for _, tc := range compatTests {
+		re := MustCompile(tc.pattern)
+		std := stdregexp.MustCompile(tc.pattern)
+
+		check := func(method, input string, got, want any) {
+			t.Helper()
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %q on %q = %#v; regexp gives %#v", method, tc.pattern, input, got, want)
+			}
+		}
+
+		check("String", "", re.String(), std.String())
+		check("NumSubexp", "", re.NumSubexp(), std.NumSubexp())
+		check("SubexpNames", "", re.SubexpNames(), std.SubexpNames())
+		check("SubexpIndex", "", re.SubexpIndex("name"), std.SubexpIndex("name"))
+		prefix, complete := re.LiteralPrefix()
+		stdPrefix, stdComplete := std.LiteralPrefix()
+		check("LiteralPrefix", "", []any{prefix, complete}, []any{stdPrefix, stdComplete})
+
+		for _, s := range tc.inputs {
+			b := []byte(s)
+			check("MatchString", s, re.MatchString(s), std.MatchString(s))
+			check("Match", s, re.Match(b), std.Match(b))
+			check("MatchReader", s, re.MatchReader(strings.NewReader(s)), std.MatchReader(strings.NewReader(s)))
+			check("Find", s, re.Find(b), std.Find(b))
+			check("FindIndex", s, re.FindIndex(b), std.FindIndex(b))
+			check("FindString", s, re.FindString(s), std.FindString(s))
+			check("FindStringIndex", s, re.FindStringIndex(s), std.FindStringIndex(s))
+			check("FindReaderIndex", s, re.FindReaderIndex(strings.NewReader(s)), std.FindReaderIndex(strings.NewReader(s)))
+			check("FindSubmatch", s, re.FindSubmatch(b), std.FindSubmatch(b))
+			check("FindSubmatchIndex", s, re.FindSubmatchIndex(b), std.FindSubmatchIndex(b))
+			check("FindStringSubmatch", s, re.FindStringSubmatch(s), std.FindStringSubmatch(s))
+			check("FindStringSubmatchIndex", s, re.FindStringSubmatchIndex(s), std.FindStringSubmatchIndex(s))
+			check("FindReaderSubmatchIndex", s, re.FindReaderSubmatchIndex(strings.NewReader(s)), std.FindReaderSubmatchIndex(strings.NewReader(s)))
+
+			for _, n := range []int{-1, 0, 1, 2} {
+				check("FindAll", s, re.FindAll(b, n), std.FindAll(b, n))
+				check("FindAllIndex", s, re.FindAllIndex(b, n), std.FindAllIndex(b, n))
+				check("FindAllString", s, re.FindAllString(s, n), std.FindAllString(s, n))
+				check("FindAllStringIndex", s, re.FindAllStringIndex(s, n), std.FindAllStringIndex(s, n))
+				check("FindAllSubmatch", s, re.FindAllSubmatch(b, n), std.FindAllSubmatch(b, n))
+				check("FindAllSubmatchIndex", s, re.FindAllSubmatchIndex(b, n), std.FindAllSubmatchIndex(b, n))
+				check("FindAllStringSubmatch", s, re.FindAllStringSubmatch(s, n), std.FindAllStringSubmatch(s, n))
+				check("FindAllStringSubmatchIndex", s, re.FindAllStringSubmatchIndex(s, n), std.FindAllStringSubmatchIndex(s, n))
+				check("Split", s, re.Split(s, n), std.Split(s, n))
+			}
+
+			for _, repl := range replacements {
+				r := []byte(repl)
+				check("ReplaceAllString", s, re.ReplaceAllString(s, repl), std.ReplaceAllString(s, repl))
+				check("ReplaceAllLiteralString", s, re.ReplaceAllLiteralString(s, repl), std.ReplaceAllLiteralString(s, repl))
+				check("ReplaceAll", s, re.ReplaceAll(b, r), std.ReplaceAll(b, r))
+				check("ReplaceAllLiteral", s, re.ReplaceAllLiteral(b, r), std.ReplaceAllLiteral(b, r))
+				if loc := std.FindSubmatchIndex(b); loc != nil {
+					check("Expand", s, re.Expand(nil, r, b, loc), std.Expand(nil, r, b, loc))
+					check("ExpandString", s, re.ExpandString(nil, repl, s, loc), std.ExpandString(nil, repl, s, loc))
+				}
+			}
+			upper := func(m string) string { return strings.ToUpper(m) + "|" }
+			check("ReplaceAllStringFunc", s, re.ReplaceAllStringFunc(s, upper), std.ReplaceAllStringFunc(s, upper))
+			upperBytes := func(m []byte) []byte { return []byte(upper(string(m))) }
+			check("ReplaceAllFunc", s, re.ReplaceAllFunc(b, upperBytes), std.ReplaceAllFunc(b, upperBytes))
+		}
+	}
+}
+
+func TestCompatUnsupported(t *testing.T) {
+	for _, pattern := range unsupportedPatterns {
+		if _, err := stdregexp.Compile(pattern); err != nil {
+			t.Errorf("standard Compile(%q) error = %v; the case tests nothing", pattern, err)
+		}
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", pattern)
+		}
+		if _, err := CompilePOSIX(pattern); err == nil {
+			t.Errorf("CompilePOSIX(%q) succeeded, want an error", pattern)
+		}
+	}
+
+	// Escaped, the same characters are literals in both packages
+	for _, pattern := range []string{`a\|b`, `ab\*`, `\(ab\)`, `a\{2}`, "[a-]", "[-a]", `a\$b`, `\^a`} {
+		if _, err := Compile(pattern); err != nil {
+			t.Errorf("Compile(%q) error = %v", pattern, err)
+		}
+	}
+}
+
+func TestCompatInvalid(t *testing.T) {
+	for _, pattern := range invalidPatterns {
+		if _, err := stdregexp.Compile(pattern); err == nil {
+			t.Errorf("standard Compile(%q) succeeded; the case tests nothing", pattern)
+		}
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestCompatPOSIX(t *testing.T) {
+	for _, tc := range compatTests {
+		re := MustCompilePOSIX(tc.pattern)
+		// The POSIX syntax has no \d or \w, so only the match mode is compared.
+		std := stdregexp.MustCompile(tc.pattern)
+		std.Longest()
+		for _, s := range tc.inputs {
+			if got, want := re.FindAllStringIndex(s, -1), std.FindAllStringIndex(s, -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("POSIX FindAllStringIndex %q on %q = %v; regexp gives %v", tc.pattern, s, got, want)
+			}
+		}
+	}
+}
+
+func TestQuoteMeta(t *testing.T) {
+	for _, s := range []string{"", "abc", `a.b*c^$\-`, "[x]{1}(y|z)+?", "é."} {
+		if got, want := QuoteMeta(s), stdregexp.QuoteMeta(s); got != want {
+			t.Errorf("QuoteMeta(%q) = %q; regexp gives %q", s, got, want)
+		}
+		if !MustCompile(QuoteMeta(s)).MatchString(s) {
+			t.Errorf("QuoteMeta(%q) does not match itself", s)
+		}
+	}
+}
+
+func TestTopLevelFunctions(t *testing.T) {
+	if ok, err := MatchString(`\d`, "a1"); !ok || err != nil {
+		t.Errorf("MatchString = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := Match(`\d`, []byte("ab")); ok || err != nil {
+		t.Errorf("Match = %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := MatchReader("b+", strings.NewReader("abb")); !ok || err != nil {
+		t.Errorf("MatchReader = %v, %v; want true, nil", ok, err)
+	}
+	if _, err := MatchString("[abc", "a"); err == nil {
+		t.Error("MatchString with an invalid pattern returned no error")
+	}
+	if _, err := Compile("[abc"); err == nil {
+		t.Error("Compile with an invalid pattern returned no error")
+	}
+}
+
+func TestMustCompilePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompile did not panic on an invalid pattern")
+		}
+	}()
+	MustCompile("[abc")
+}
+
+func TestTextMarshaling(t *testing.T) {
+	re := MustCompile(`a\d+`)
+	text, err := re.MarshalText()
+	if err != nil || string(text) != `a\d+` {
+		t.Fatalf("MarshalText = %q, %v", text, err)
+	}
+	if got, _ := re.AppendText([]byte("re:")); string(got) != `re:a\d+` {
+		t.Errorf("AppendText = %q", got)
+	}
+
+	var back Regexp
+	if err := back.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText: %v", err)
+	}
+	if !back.MatchString("xa12") || back.MatchString("ab") {
+		t.Errorf("unmarshaled Regexp %q matches the wrong strings", back.String())
+	}
+	if err := back.UnmarshalText([]byte("[")); err == nil {
+		t.Error("UnmarshalText with an invalid pattern returned no error")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	re := MustCompilePOSIX("a+")
+	c := re.Copy()
+	if c == re || c.String() != re.String() {
+		t.Fatalf("Copy = %p %q; want a distinct Regexp for %q", c, c.String(), re.String())
+	}
+	if got := c.FindString("baaa"); got != "aaa" {
+		t.Errorf("Copy FindString = %q; want %q", got, "aaa")
+	}
+}
+
+// TestNoStepLimit checks that the shim never gives up on a search, which it has
+// no way to report.
+func TestNoStepLimit(t *testing.T) {
+	re := MustCompile("a?a?a?a?a?a?a?a?a?a?a?a?a?a?a?a?a?a?a?a?aaaaaaaaaaaaaaaaaaaab")
+	input := strings.Repeat("a", 5000)
+	if re.MatchString(input) {
+		t.Error("matched input without a b")
+	}
+	if !re.MatchString(input + "b") {
+		t.Error("did not match input ending in b")
+	}
+}